@@ -0,0 +1,47 @@
+package argon2id
+
+import (
+	argon2core "github.com/gohango/argon2id/argon2id/internal/argon2"
+)
+
+// arenaPool keeps a bounded number of argon2 memory arenas for reuse, so that hashing does not allocate its memory on
+// every call. The arenas are zeroed after every use by the calculation itself.
+type arenaPool struct {
+	free chan *argon2core.Arena
+}
+
+// newArenaPool returns a pool keeping up to size idle arenas.
+func newArenaPool(size int) *arenaPool {
+	return &arenaPool{free: make(chan *argon2core.Arena, size)}
+}
+
+// get returns an idle arena of at least memory KiB, or a new one when there is none.
+// Idle arenas that are too small are dropped, so that the pool follows the largest memory in use.
+// A nil pool returns nil, leaving the allocation to the calculation.
+func (p *arenaPool) get(memory uint32) *argon2core.Arena {
+	if p == nil {
+		return nil
+	}
+
+	select {
+	case a := <-p.free:
+		if a.Memory() >= memory {
+			return a
+		}
+	default:
+	}
+
+	return argon2core.NewArena(memory)
+}
+
+// put gives back an arena returned by get, dropping it when the pool is full.
+func (p *arenaPool) put(a *argon2core.Arena) {
+	if p == nil {
+		return
+	}
+
+	select {
+	case p.free <- a:
+	default:
+	}
+}
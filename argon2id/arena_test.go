@@ -0,0 +1,101 @@
+package argon2id
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestArenaPool(t *testing.T) {
+	var none *arenaPool
+	if a := none.get(64); a != nil {
+		t.Errorf("arenaPool.get() = %v on a nil pool, expectation = nil", a)
+	}
+	none.put(nil)
+
+	p := newArenaPool(1)
+	a := p.get(64)
+	if a.Memory() != 64 {
+		t.Errorf("arenaPool.get() memory = %d, expectation = 64", a.Memory())
+	}
+	p.put(a)
+
+	// A smaller memory reuses the idle arena.
+	if b := p.get(32); b != a {
+		t.Errorf("arenaPool.get() = %p, expected the idle arena %p", b, a)
+	}
+	p.put(a)
+
+	// A larger memory replaces it.
+	b := p.get(128)
+	if b == a || b.Memory() != 128 {
+		t.Errorf("arenaPool.get() = %p of %d KiB, expected a new arena of 128 KiB", b, b.Memory())
+	}
+	p.put(b)
+
+	// The pool is full.
+	p.put(p.get(128))
+	p.put(a)
+	if c := p.get(128); c != b {
+		t.Errorf("arenaPool.get() = %p, expected the idle arena %p", c, b)
+	}
+}
+
+func TestHasherArenaPool(t *testing.T) {
+	p := &Params{Memory: 256, Iterations: 2, Parallelism: 2, SaltLength: 16, KeyLength: 32}
+	salt := bytes.Repeat([]byte{0x2a}, 16)
+
+	expected, err := NewHasher(WithRand(bytes.NewReader(salt)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := expected.Generate([]byte("foo123"), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHasher(WithRand(bytes.NewReader(salt)), WithArenaPool(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := h.Generate([]byte("foo123"), p)
+	if err != nil {
+		t.Fatalf("Hasher.Generate() error = %v", err)
+	}
+	if hash != want {
+		t.Errorf("Hasher.Generate() = %s, expectation = %s", hash, want)
+	}
+
+	// The arena is reused for every comparison.
+	for _, pass := range []string{"foo123", "foo124", "foo123"} {
+		err = h.Compare(want, []byte(pass))
+		if (err == nil) != (pass == "foo123") {
+			t.Errorf("Hasher.Compare(%q) error = %v", pass, err)
+		}
+	}
+
+	if _, err = NewHasher(WithArenaPool(0)); err != ErrInvalidOption {
+		t.Errorf("NewHasher() error = %v, expectation = %v", err, ErrInvalidOption)
+	}
+}
+
+func benchmarkGenerate(b *testing.B, h *Hasher, p *Params) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := h.Generate([]byte("password"), p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkGenerateArenaPool(b *testing.B) {
+	pooled, err := NewHasher(WithArenaPool(1))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, preset := range []Preset{PresetOWASP, PresetRFC9106Second} {
+		p := preset.Params
+		b.Run(preset.Name+"/GenerateFromPassword", func(b *testing.B) { benchmarkGenerate(b, defaultHasher, &p) })
+		b.Run(preset.Name+"/ArenaPool", func(b *testing.B) { benchmarkGenerate(b, pooled, &p) })
+	}
+}
@@ -1,26 +1,33 @@
 // Package argon2id provides functions to deal with argon2id password protection.
+// The argon2i and argon2d variants are supported as well, mainly to verify hashes imported from other systems.
 package argon2id
 
 import (
-	"crypto/rand"
+	"context"
 	"crypto/subtle"
-	"encoding/base64"
 	"errors"
-	"fmt"
-	"io"
-	"strings"
+	"strconv"
 
-	"golang.org/x/crypto/argon2"
+	argon2core "github.com/gohango/argon2id/argon2id/internal/argon2"
 )
 
 var (
 	ErrInvalidHash         = errors.New("the encoded hash is not in the correct format")
 	ErrIncompatibleVersion = errors.New("incompatible version of argon2")
 	ErrPasswordNotMatch    = errors.New("passwords do not match")
+	ErrSaltTooShort        = errors.New("the salt must be at least 8 bytes long")
+	ErrKeyTooShort         = errors.New("the key must be at least 4 bytes long")
+	ErrInvalidParallelism  = errors.New("the parallelism must be between 1 and 2^24-1")
+	ErrMemoryTooLow        = errors.New("the memory must be at least 8 KiB per degree of parallelism")
+	ErrInvalidIterations   = errors.New("the number of iterations must be at least 1")
+	ErrLimitExceeded       = errors.New("the hash parameters exceed the verification limits")
+	ErrUnknownKeyID        = errors.New("the hash was generated with an unknown pepper")
+	ErrOverloaded          = errors.New("too many hashing operations are waiting for memory")
 )
 
 // Params stores the argon2 parameters.
 type Params struct {
+	Variant     Variant
 	Memory      uint32
 	Iterations  uint32
 	Parallelism uint8
@@ -28,103 +35,424 @@ type Params struct {
 	KeyLength   uint32
 }
 
-// decodeHash decodes the argon2 hash and returns the protection parameters.
-func decodeHash(hash string) (p *Params, salt []byte, hashedPassword []byte, err error) {
+// Validate checks the parameters against the bounds defined by RFC 9106.
+// Returns nil when the parameters are usable, or an error describing the first violated bound.
+func (p *Params) Validate() error {
+	if !p.Variant.valid() {
+		return ErrIncompatibleVersion
+	}
+
+	if p.SaltLength < 8 {
+		return ErrSaltTooShort
+	}
+
+	if p.KeyLength < 4 {
+		return ErrKeyTooShort
+	}
+
+	// Parallelism is stored in a uint8, so the upper bound of 2^24-1 always holds.
+	if p.Parallelism < 1 {
+		return ErrInvalidParallelism
+	}
+
+	if p.Memory < 8*uint32(p.Parallelism) {
+		return ErrMemoryTooLow
+	}
+
+	if p.Iterations < 1 {
+		return ErrInvalidIterations
+	}
+
+	return nil
+}
+
+// defaultParams returns a copy of the parameters of DefaultPreset, used when none are given.
+func defaultParams() *Params {
+	p := DefaultPreset().Params
+	return &p
+}
+
+// DefaultMaxBcryptCost is the maximum cost of wrapped bcrypt hashes when Limits leaves MaxBcryptCost zero.
+const DefaultMaxBcryptCost = 14
+
+// Limits stores the maximum argon2 parameters accepted when verifying a hash.
+// A zero field means that parameter is not limited, except MaxBcryptCost, which then defaults to DefaultMaxBcryptCost.
+// MaxBcryptCost limits the cost of wrapped bcrypt hashes, as well as the cost of the bcrypt hashes verified by a Registry.
+type Limits struct {
+	MaxMemory      uint32
+	MaxIterations  uint32
+	MaxParallelism uint8
+	MaxKeyLength   uint32
+	MaxBcryptCost  uint8
+}
+
+// check returns a LimitError for the first parameter of the hash above its limit, if any.
+func (l *Limits) check(h *Hash) error {
+	if l == nil {
+		return nil
+	}
+
+	p := &h.Params
+	switch {
+	case l.MaxMemory > 0 && p.Memory > l.MaxMemory:
+		return &LimitError{Param: "m", Value: uint64(p.Memory), Max: uint64(l.MaxMemory)}
+	case l.MaxIterations > 0 && p.Iterations > l.MaxIterations:
+		return &LimitError{Param: "t", Value: uint64(p.Iterations), Max: uint64(l.MaxIterations)}
+	case l.MaxParallelism > 0 && p.Parallelism > l.MaxParallelism:
+		return &LimitError{Param: "p", Value: uint64(p.Parallelism), Max: uint64(l.MaxParallelism)}
+	case l.MaxKeyLength > 0 && p.KeyLength > l.MaxKeyLength:
+		return &LimitError{Param: "keylen", Value: uint64(p.KeyLength), Max: uint64(l.MaxKeyLength)}
+	case h.Wrap != nil && h.Wrap.Cost > l.maxBcryptCost():
+		return &LimitError{Param: "wcost", Value: uint64(h.Wrap.Cost), Max: uint64(l.maxBcryptCost())}
+	}
+
+	return nil
+}
+
+// maxBcryptCost returns MaxBcryptCost, or DefaultMaxBcryptCost when it is zero.
+func (l *Limits) maxBcryptCost() int {
+	if l.MaxBcryptCost == 0 {
+		return DefaultMaxBcryptCost
+	}
+
+	return int(l.MaxBcryptCost)
+}
+
+// Hash stores the decoded parts of an argon2 hash.
+// KeyID identifies the pepper mixed into the key, and is empty for hashes generated without one.
+// Data is the associated data the hash is bound to, and is empty for hashes generated without any.
+// Normalization is the normalization applied to the password, such as NormalizationOpaqueString, and is empty for
+// hashes of the raw password.
+// Wrap describes the legacy hash the key is derived from, and is nil for hashes derived from the password itself.
+type Hash struct {
+	Version       int
+	Params        Params
+	KeyID         []byte
+	Data          []byte
+	Normalization string
+	Wrap          *Wrapping
+	Salt          []byte
+	Key           []byte
+}
+
+// Parse decodes the string representation of an argon2 hash, following the PHC string format.
+// Returns the decoded hash with nil error when successful. On failure, it returns nil with non-nil error.
+func Parse(hash string) (*Hash, error) {
+	return decodeHash(hash)
+}
+
+// Algorithm returns the name of the algorithm used to produce the hash.
+func (h *Hash) Algorithm() string {
+	return h.Params.Variant.String()
+}
+
+// String returns the canonical string representation of the hash, as produced by GenerateFromPassword.
+func (h *Hash) String() string {
+	ps := &phcString{
+		id:      h.Algorithm(),
+		version: strconv.Itoa(h.Version),
+		params: []phcParam{
+			{name: "m", value: strconv.FormatUint(uint64(h.Params.Memory), 10)},
+			{name: "t", value: strconv.FormatUint(uint64(h.Params.Iterations), 10)},
+			{name: "p", value: strconv.FormatUint(uint64(h.Params.Parallelism), 10)},
+		},
+		salt: b64.EncodeToString(h.Salt),
+		hash: b64.EncodeToString(h.Key),
+	}
+	if len(h.KeyID) > 0 {
+		ps.params = append(ps.params, phcParam{name: "keyid", value: b64.EncodeToString(h.KeyID)})
+	}
+	if len(h.Data) > 0 {
+		ps.params = append(ps.params, phcParam{name: "data", value: b64.EncodeToString(h.Data)})
+	}
+	if h.Normalization != "" {
+		ps.params = append(ps.params, phcParam{name: "norm", value: h.Normalization})
+	}
+	if h.Wrap != nil {
+		ps.params = append(ps.params, h.Wrap.params()...)
+	}
+
+	return ps.String()
+}
+
+// decodeHash decodes the argon2 hash and returns its parts along with the protection parameters.
+func decodeHash(hash string) (*Hash, error) {
 	// Example of argon2id hash
 	// $argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM
-	// We need to separate the string by $ sign to retrieve:
-	// [0] Empty string
-	// [1] The algorithm name (argon2id)
-	// [2] The version
-	// [3] The Memory usage, Iterations, and Parallelism
-	// [4] The salt
-	// [5] The hashed password
+	// The PHC string holds:
+	// - The algorithm name (argon2id, argon2i or argon2d)
+	// - The version, 16 when omitted
+	// - The Memory usage, Iterations, and Parallelism, optionally with the pepper key identifier, associated data,
+	//   password normalization and wrapped legacy hash
+	// - The salt
+	// - The hashed password
+	ps, err := parsePHC(hash)
+	if err != nil {
+		return nil, err
+	}
 
-	elems := strings.Split(hash, "$")
-	if len(elems) != 6 {
-		return nil, nil, nil, ErrInvalidHash
+	variant, ok := parseVariant(ps.id)
+	if !ok {
+		return nil, &UnsupportedError{Field: FieldAlgorithm, Value: ps.id, Err: ErrIncompatibleVersion}
 	}
 
 	// Check the version number.
-	var ver int
-	_, err = fmt.Sscanf(elems[2], "v=%d", &ver)
-	if err != nil {
-		return nil, nil, nil, err
+	h := &Hash{Version: argon2core.Version10, Params: Params{Variant: variant}}
+	if ps.version != "" {
+		ver, err := parseDecimal(ps.version, 32)
+		if err != nil {
+			return nil, parseError(FieldVersion, err)
+		}
+		h.Version = int(ver)
 	}
-
-	if elems[1] != "argon2id" || ver != argon2.Version {
-		return nil, nil, nil, ErrIncompatibleVersion
+	if h.Version != argon2core.Version10 && h.Version != argon2core.Version13 {
+		return nil, &UnsupportedError{Field: FieldVersion, Value: ps.version, Err: ErrIncompatibleVersion}
 	}
 
 	// Build the parameters.
-	p = &Params{}
-	_, err = fmt.Sscanf(elems[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
-	if err != nil {
-		return nil, nil, nil, err
+	if err = decodeParams(ps.params, h); err != nil {
+		return nil, parseError(FieldParams, err)
+	}
+
+	// Both the salt and the hashed password are needed to verify a password.
+	if ps.salt == "" {
+		return nil, &ParseError{Field: FieldSalt, Err: ErrInvalidHash}
+	}
+	if ps.hash == "" {
+		return nil, &ParseError{Field: FieldKey, Err: ErrInvalidHash}
 	}
 
-	salt, err = base64.RawStdEncoding.DecodeString(elems[4])
+	h.Salt, err = decodeB64(ps.salt)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, parseError(FieldSalt, err)
 	}
-	p.SaltLength = uint32(len(salt))
+	h.Params.SaltLength = uint32(len(h.Salt))
 
-	hashedPassword, err = base64.RawStdEncoding.DecodeString(elems[5])
+	h.Key, err = decodeB64(ps.hash)
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, parseError(FieldKey, err)
+	}
+	h.Params.KeyLength = uint32(len(h.Key))
+
+	if err = h.Params.Validate(); err != nil {
+		switch err {
+		case ErrSaltTooShort:
+			return nil, parseError(FieldSalt, err)
+		case ErrKeyTooShort:
+			return nil, parseError(FieldKey, err)
+		default:
+			return nil, parseError(FieldParams, err)
+		}
 	}
-	p.KeyLength = uint32(len(hashedPassword))
 
-	return p, salt, hashedPassword, nil
+	return h, nil
 }
 
-// CompareHashAndPassword compares a argon2id hashed password with its possible plaintext equivalent.
-// Returns nil on success, or an error on failure.
-func CompareHashAndPassword(hash string, pass []byte) error {
-	p, salt, hashedPass, err := decodeHash(hash)
+// decodeParams decodes the m, t and p costs and the optional keyid, data, normalization and wrapping parameters into h.
+// The parameters may come in any order, but each of them at most once.
+func decodeParams(params []phcParam, h *Hash) error {
+	seen := make(map[string]bool, len(params))
+	wrap := make(map[string]string)
+	for _, param := range params {
+		if seen[param.name] {
+			return ErrInvalidHash
+		}
+		seen[param.name] = true
+
+		var err error
+		switch param.name {
+		case "m":
+			var m uint64
+			m, err = parseDecimal(param.value, 32)
+			h.Params.Memory = uint32(m)
+		case "t":
+			var t uint64
+			t, err = parseDecimal(param.value, 32)
+			h.Params.Iterations = uint32(t)
+		case "p":
+			var p uint64
+			p, err = parseDecimal(param.value, 8)
+			h.Params.Parallelism = uint8(p)
+		case "keyid":
+			h.KeyID, err = decodeB64(param.value)
+		case "data":
+			h.Data, err = decodeB64(param.value)
+		case "norm":
+			h.Normalization, err = decodeNormalization(param.value)
+		case "wrap", "wcost", "wsalt":
+			wrap[param.name] = param.value
+		default:
+			return ErrInvalidHash
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if !seen["m"] || !seen["t"] || !seen["p"] {
+		return ErrInvalidHash
+	}
+
+	if len(wrap) > 0 {
+		// Wrapped legacy hashes are computed from the raw password, so they are never normalized.
+		if seen["norm"] {
+			return ErrInvalidHash
+		}
+
+		var err error
+		if h.Wrap, err = decodeWrapping(wrap); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// engine stores the resources used to calculate keys. The zero engine calculates them without bounds.
+type engine struct {
+	// limiter admits the calculations based on their memory, and is nil when unbounded.
+	limiter *limiter
+	// threads bounds the number of lanes of a calculation computed at once, and is zero when unbounded.
+	threads int
+	// arenas provides the memory of the calculations, and is nil when they allocate it.
+	arenas *arenaPool
+	// pool runs the lanes of the calculations, and is nil when each lane runs on its own goroutine.
+	pool *argon2core.Pool
+}
+
+// deriveKey calculates the argon2 key of the password using the version, variant, costs, salt and associated data of h.
+// A non-empty secret is mixed into the key as the argon2 secret value K.
+// The calculation uses the resources of e, whose limiter admits it based on its memory.
+// Returns the context error when ctx is done before the key is calculated.
+func deriveKey(ctx context.Context, e *engine, pass, secret []byte, h *Hash) ([]byte, error) {
+	p := &h.Params
+	if err := e.limiter.acquire(ctx, uint64(p.Memory)); err != nil {
+		return nil, err
+	}
+	defer e.limiter.release(uint64(p.Memory))
+
+	// Every key is derived by the internal core.
+	arena := e.arenas.get(p.Memory)
+	defer e.arenas.put(arena)
+
+	return argon2core.Derive(ctx, &argon2core.Input{
+		Mode:     p.Variant.mode(),
+		Version:  uint32(h.Version),
+		Password: pass,
+		Salt:     h.Salt,
+		Secret:   secret,
+		Data:     h.Data,
+		Time:     p.Iterations,
+		Memory:   p.Memory,
+		Lanes:    uint32(p.Parallelism),
+		KeyLen:   p.KeyLength,
+		Threads:  e.threads,
+		Arena:    arena,
+		Pool:     e.pool,
+	})
+}
+
+// compareHash compares the decoded hash with the key derived from the password, the given secret and associated data.
+// The associated data recorded in the hash is informative only: the caller's data is used, so that a hash moved to
+// another account fails to verify.
+func compareHash(ctx context.Context, e *engine, h *Hash, pass, secret, data []byte) error {
+	// A password the normalization does not allow cannot have been hashed with it.
+	pass, err := normalize(h.Normalization, pass)
+	if errors.Is(err, ErrInvalidPassword) {
+		return ErrPasswordNotMatch
+	}
 	if err != nil {
 		return err
 	}
 
+	// Wrapped hashes are derived from the legacy hash of the password.
+	if h.Wrap != nil {
+		if pass, err = h.Wrap.inner(pass); err != nil {
+			return err
+		}
+	}
+
 	// Let's calculate the hash from the user provided password.
-	userHash := argon2.IDKey(pass, salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	expected := *h
+	expected.Data = data
+	userHash, err := deriveKey(ctx, e, pass, secret, &expected)
+	if err != nil {
+		return err
+	}
 
 	// Let's compare the hash values.
-	if subtle.ConstantTimeCompare(userHash, hashedPass) == 0 {
+	if subtle.ConstantTimeCompare(userHash, h.Key) == 0 {
 		return ErrPasswordNotMatch
 	}
 
 	return nil
 }
 
+// CompareHashAndPassword compares a argon2id hashed password with its possible plaintext equivalent.
+// Returns nil on success, or an error on failure.
+func CompareHashAndPassword(hash string, pass []byte) error {
+	return defaultHasher.Compare(hash, pass)
+}
+
+// CompareHashAndPasswordWithLimits works like CompareHashAndPassword, but refuses to verify hashes whose parameters
+// exceed the given limits. Such hashes are rejected with ErrLimitExceeded before any memory is allocated for the comparison.
+// A nil l disables the limits.
+func CompareHashAndPasswordWithLimits(hash string, pass []byte, l *Limits) error {
+	return defaultHasher.compare(context.Background(), hash, pass, nil, l)
+}
+
+// CompareHashAndPasswordContext works like CompareHashAndPassword, but stops the comparison as soon as ctx is done.
+// Returns the context error in that case.
+func CompareHashAndPasswordContext(ctx context.Context, hash string, pass []byte) error {
+	return defaultHasher.CompareContext(ctx, hash, pass)
+}
+
+// CompareNoUser runs a full-cost verification of the password against a dummy hash with the parameters used by
+// GenerateFromPassword when none are given, and returns ErrPasswordNotMatch.
+// Call it when the user to authenticate does not exist, so that the response takes about as long as
+// CompareHashAndPassword for an existing user and does not reveal which accounts exist.
+func CompareNoUser(pass []byte) error {
+	return defaultHasher.CompareNoUser(pass)
+}
+
+// CompareHashAndPasswordWithAD works like CompareHashAndPassword for hashes generated by GenerateFromPasswordWithAD.
+// The comparison fails with ErrPasswordNotMatch unless ad is the associated data the hash was generated with.
+func CompareHashAndPasswordWithAD(hash string, pass, ad []byte) error {
+	return defaultHasher.CompareWithAD(hash, pass, ad)
+}
+
 // GenerateFromPassword generates the string representation of argon2id from the given password and parameters.
+// The variant of the parameters selects another algorithm than argon2id.
 // Returns the string representation with nil error when successful. On failure, it returns empty string with non-nil error.
 func GenerateFromPassword(pass []byte, p *Params) (string, error) {
-	if p == nil {
-		// We will use default configuration here.
-		p = &Params{
-			Memory:      4096,
-			Iterations:  10,
-			Parallelism: 2,
-			SaltLength:  32,
-			KeyLength:   64,
-		}
-	}
-
-	// Generate the salt.
-	unencodedSalt := make([]byte, p.SaltLength)
+	return defaultHasher.Generate(pass, p)
+}
 
-	_, err := io.ReadFull(rand.Reader, unencodedSalt)
-	if err != nil {
-		return "", err
-	}
+// GenerateFromPasswordContext works like GenerateFromPassword, but stops hashing as soon as ctx is done.
+// Returns the context error in that case.
+func GenerateFromPasswordContext(ctx context.Context, pass []byte, p *Params) (string, error) {
+	return defaultHasher.GenerateContext(ctx, pass, p)
+}
 
-	// Generate the hashed password.
-	hash := argon2.IDKey(pass, unencodedSalt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+// GenerateFromPasswordWithAD works like GenerateFromPassword, but binds the hash to the associated data ad,
+// such as a user or tenant identifier. The hash then only verifies with CompareHashAndPasswordWithAD and the same ad,
+// so that it cannot be moved to another account. The associated data is recorded in the hash with the data parameter.
+func GenerateFromPasswordWithAD(pass, ad []byte, p *Params) (string, error) {
+	return defaultHasher.GenerateWithAD(pass, ad, p)
+}
 
-	// Generate the string representation.
-	encodedSalt := base64.RawStdEncoding.EncodeToString(unencodedSalt)
-	encodedHash := base64.RawStdEncoding.EncodeToString(hash)
+// NeedsRehash reports whether the given argon2 hash was generated with another variant or with parameters weaker than p,
+// or wraps a legacy hash.
+// A nil p is compared against the defaults used by GenerateFromPassword.
+// Returns an error when the hash cannot be decoded.
+func NeedsRehash(hash string, p *Params) (bool, error) {
+	return defaultHasher.NeedsRehash(hash, p)
+}
 
-	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, p.Memory, p.Iterations, p.Parallelism, encodedSalt, encodedHash), nil
+// VerifyAndUpgrade compares a argon2id hashed password with its possible plaintext equivalent and, only when they match,
+// re-hashes the password with the target parameters if the stored ones are weaker or the hash wraps a legacy hash.
+// Returns the hash to store, which is the given hash when no upgrade was needed, and whether it was upgraded.
+// On failure, it returns empty string with non-nil error.
+func VerifyAndUpgrade(hash string, pass []byte, target *Params) (newHash string, upgraded bool, err error) {
+	return defaultHasher.VerifyAndUpgrade(hash, pass, target)
 }
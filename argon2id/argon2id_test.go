@@ -1,8 +1,13 @@
 package argon2id
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
+
+	argon2core "github.com/gohango/argon2id/argon2id/internal/argon2"
 )
 
 func TestCompareHashAndPassword(t *testing.T) {
@@ -26,30 +31,75 @@ func TestCompareHashAndPassword(t *testing.T) {
 			expectedErr: nil,
 		},
 		{
-			name: "Not an argon2id hash",
+			name: "Must verify an argon2i hash",
+			args: args{
+				hash: "$argon2i$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$wWKIMhR9lyDFvRz9YTZweHKfbftvj+qf+YFY4NeBbtA",
+				pass: []byte("password"),
+			},
+			wantErr:     false,
+			expectedErr: nil,
+		},
+		{
+			name: "Must verify an argon2d hash",
+			args: args{
+				hash: "$argon2d$v=19$m=64,t=2,p=1$c29tZXNhbHQ$O+nseaabddN1KstZofu4spWkZSnEj7t1",
+				pass: []byte("password"),
+			},
+			wantErr:     false,
+			expectedErr: nil,
+		},
+		{
+			name: "Variant is part of the hash",
 			args: args{
 				hash: "$argon2i$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
 				pass: []byte("foo123"),
 			},
 			wantErr:     true,
+			expectedErr: ErrPasswordNotMatch,
+		},
+		{
+			name: "Not an argon2 hash",
+			args: args{
+				hash: "$argon2x$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+				pass: []byte("foo123"),
+			},
+			wantErr:     true,
 			expectedErr: ErrIncompatibleVersion,
 		},
 		{
 			name: "Invalid hash format",
 			args: args{
-				hash: "$argon2id$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+				hash: "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw",
 				pass: []byte("foo123"),
 			},
 			wantErr:     true,
 			expectedErr: ErrInvalidHash,
 		},
 		{
-			name: "Passwords do not match",
+			name: "Must verify a version 16 hash without version field",
+			args: args{
+				hash: "$argon2i$m=65536,t=2,p=1$c29tZXNhbHQ$9sTbSlTio3Biev89thdrlKKiCaYsjjYVJxGAL3swxpQ",
+				pass: []byte("password"),
+			},
+			wantErr:     false,
+			expectedErr: nil,
+		},
+		{
+			name: "Non-canonical encoding",
 			args: args{
 				hash: "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJym",
 				pass: []byte("foo123"),
 			},
 			wantErr:     true,
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name: "Passwords do not match",
+			args: args{
+				hash: "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJzM",
+				pass: []byte("foo123"),
+			},
+			wantErr:     true,
 			expectedErr: ErrPasswordNotMatch,
 		},
 	}
@@ -61,7 +111,7 @@ func TestCompareHashAndPassword(t *testing.T) {
 				t.Errorf("CompareHashAndPassword() error = %v, wantErr = %v", err, tt.wantErr)
 			}
 
-			if tt.wantErr && err != tt.expectedErr {
+			if tt.wantErr && !errors.Is(err, tt.expectedErr) {
 				t.Errorf("CompareHashAndPassword() error = %v, expectation = %v", err, tt.expectedErr)
 			}
 		})
@@ -74,9 +124,10 @@ func TestGenerateFromPassword(t *testing.T) {
 		params *Params
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name        string
+		args        args
+		wantErr     bool
+		expectedErr error
 	}{
 		{
 			name: "Corret password",
@@ -85,13 +136,51 @@ func TestGenerateFromPassword(t *testing.T) {
 				params: &Params{
 					Memory:      4096,
 					Iterations:  1000,
-					Parallelism: 0,
+					Parallelism: 1,
 					SaltLength:  32,
 					KeyLength:   64,
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name: "Default parameters",
+			args: args{
+				pass:   []byte("foo123"),
+				params: nil,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Argon2d variant",
+			args: args{
+				pass: []byte("foo123"),
+				params: &Params{
+					Variant:     Argon2d,
+					Memory:      4096,
+					Iterations:  3,
+					Parallelism: 2,
+					SaltLength:  16,
+					KeyLength:   32,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid parallelism",
+			args: args{
+				pass: []byte("foo123"),
+				params: &Params{
+					Memory:      4096,
+					Iterations:  1000,
+					Parallelism: 0,
+					SaltLength:  32,
+					KeyLength:   64,
+				},
+			},
+			wantErr:     true,
+			expectedErr: ErrInvalidParallelism,
+		},
 	}
 
 	for _, tt := range tests {
@@ -103,9 +192,548 @@ func TestGenerateFromPassword(t *testing.T) {
 				t.Errorf("GenerateFromPassword() error = %v, wantErr = %v", err, tt.wantErr)
 			}
 
+			if tt.wantErr {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("GenerateFromPassword() error = %v, expectation = %v", err, tt.expectedErr)
+				}
+				return
+			}
+
 			if err = CompareHashAndPassword(str, tt.args.pass); err != nil {
 				t.Errorf("GenerateFromPassword() Failed to compare hashed password with the real password. %v.", err)
 			}
 		})
 	}
 }
+
+func TestParamsValidate(t *testing.T) {
+	valid := Params{Memory: 64, Iterations: 1, Parallelism: 8, SaltLength: 8, KeyLength: 4}
+
+	tests := []struct {
+		name        string
+		modify      func(p *Params)
+		expectedErr error
+	}{
+		{
+			name:        "Minimum valid parameters",
+			modify:      func(p *Params) {},
+			expectedErr: nil,
+		},
+		{
+			name:        "Salt too short",
+			modify:      func(p *Params) { p.SaltLength = 7 },
+			expectedErr: ErrSaltTooShort,
+		},
+		{
+			name:        "Key too short",
+			modify:      func(p *Params) { p.KeyLength = 3 },
+			expectedErr: ErrKeyTooShort,
+		},
+		{
+			name:        "No parallelism",
+			modify:      func(p *Params) { p.Parallelism = 0 },
+			expectedErr: ErrInvalidParallelism,
+		},
+		{
+			name:        "Memory below 8 KiB per lane",
+			modify:      func(p *Params) { p.Memory = 63 },
+			expectedErr: ErrMemoryTooLow,
+		},
+		{
+			name:        "No iterations",
+			modify:      func(p *Params) { p.Iterations = 0 },
+			expectedErr: ErrInvalidIterations,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid
+			tt.modify(&p)
+
+			if err := p.Validate(); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Params.Validate() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		hash        string
+		want        Params
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name: "Must decode a valid hash",
+			hash: "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			want: Params{
+				Memory:      4096,
+				Iterations:  3,
+				Parallelism: 1,
+				SaltLength:  16,
+				KeyLength:   32,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Must decode a peppered hash",
+			hash: "$argon2id$v=19$m=4096,t=3,p=1,keyid=azE$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			want: Params{
+				Memory:      4096,
+				Iterations:  3,
+				Parallelism: 1,
+				SaltLength:  16,
+				KeyLength:   32,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Must decode an argon2d hash",
+			hash: "$argon2d$v=19$m=64,t=2,p=1$c29tZXNhbHQ$O+nseaabddN1KstZofu4spWkZSnEj7t1",
+			want: Params{
+				Variant:     Argon2d,
+				Memory:      64,
+				Iterations:  2,
+				Parallelism: 1,
+				SaltLength:  8,
+				KeyLength:   24,
+			},
+			wantErr: false,
+		},
+		{
+			name:        "Not an argon2 hash",
+			hash:        "$argon2x$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			wantErr:     true,
+			expectedErr: ErrIncompatibleVersion,
+		},
+		{
+			name:        "Invalid hash format",
+			hash:        "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw",
+			wantErr:     true,
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Invalid parameters",
+			hash:        "$argon2id$v=19$m=4096,t=3,p=0$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			wantErr:     true,
+			expectedErr: ErrInvalidParallelism,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := Parse(tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Parse() error = %v, expectation = %v", err, tt.expectedErr)
+				}
+				return
+			}
+
+			if h.Algorithm() != tt.want.Variant.String() || h.Version != 19 {
+				t.Errorf("Parse() algorithm = %s, version = %d", h.Algorithm(), h.Version)
+			}
+
+			if h.Params != tt.want {
+				t.Errorf("Parse() params = %+v, expectation = %+v", h.Params, tt.want)
+			}
+
+			if got := h.String(); got != tt.hash {
+				t.Errorf("Hash.String() = %s, expectation = %s", got, tt.hash)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	const hash = "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM"
+
+	tests := []struct {
+		name    string
+		hash    string
+		params  *Params
+		want    bool
+		wantErr bool
+	}{
+		{
+			name:   "Same parameters",
+			hash:   hash,
+			params: &Params{Memory: 4096, Iterations: 3, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+			want:   false,
+		},
+		{
+			name:   "Weaker target parameters",
+			hash:   hash,
+			params: &Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 8, KeyLength: 16},
+			want:   false,
+		},
+		{
+			name:   "Memory below target",
+			hash:   hash,
+			params: &Params{Memory: 8192, Iterations: 3, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+			want:   true,
+		},
+		{
+			name:   "Iterations below target",
+			hash:   hash,
+			params: &Params{Memory: 4096, Iterations: 4, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+			want:   true,
+		},
+		{
+			name:   "Parallelism below target",
+			hash:   hash,
+			params: &Params{Memory: 4096, Iterations: 3, Parallelism: 2, SaltLength: 16, KeyLength: 32},
+			want:   true,
+		},
+		{
+			name:   "Salt length below target",
+			hash:   hash,
+			params: &Params{Memory: 4096, Iterations: 3, Parallelism: 1, SaltLength: 32, KeyLength: 32},
+			want:   true,
+		},
+		{
+			name:   "Key length below target",
+			hash:   hash,
+			params: &Params{Memory: 4096, Iterations: 3, Parallelism: 1, SaltLength: 16, KeyLength: 64},
+			want:   true,
+		},
+		{
+			name:   "Variant differs from target",
+			hash:   hash,
+			params: &Params{Variant: Argon2i, Memory: 4096, Iterations: 3, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+			want:   true,
+		},
+		{
+			name:   "Default parameters",
+			hash:   hash,
+			params: nil,
+			want:   true,
+		},
+		{
+			name:    "Invalid hash format",
+			hash:    "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NeedsRehash(tt.hash, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NeedsRehash() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("NeedsRehash() = %v, expectation = %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyAndUpgrade(t *testing.T) {
+	const hash = "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM"
+
+	type args struct {
+		hash   string
+		pass   []byte
+		target *Params
+	}
+	tests := []struct {
+		name         string
+		args         args
+		wantUpgraded bool
+		wantErr      bool
+		expectedErr  error
+	}{
+		{
+			name: "Up to date hash is kept",
+			args: args{
+				hash:   hash,
+				pass:   []byte("foo123"),
+				target: &Params{Memory: 4096, Iterations: 3, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+			},
+			wantUpgraded: false,
+		},
+		{
+			name: "Weaker hash is upgraded",
+			args: args{
+				hash:   hash,
+				pass:   []byte("foo123"),
+				target: &Params{Memory: 8192, Iterations: 3, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+			},
+			wantUpgraded: true,
+		},
+		{
+			name: "Wrong password is never upgraded",
+			args: args{
+				hash:   hash,
+				pass:   []byte("foo124"),
+				target: &Params{Memory: 8192, Iterations: 3, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+			},
+			wantErr:     true,
+			expectedErr: ErrPasswordNotMatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newHash, upgraded, err := VerifyAndUpgrade(tt.args.hash, tt.args.pass, tt.args.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VerifyAndUpgrade() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("VerifyAndUpgrade() error = %v, expectation = %v", err, tt.expectedErr)
+				}
+				if newHash != "" || upgraded {
+					t.Errorf("VerifyAndUpgrade() = %q, %v on failure", newHash, upgraded)
+				}
+				return
+			}
+
+			if upgraded != tt.wantUpgraded {
+				t.Errorf("VerifyAndUpgrade() upgraded = %v, expectation = %v", upgraded, tt.wantUpgraded)
+			}
+
+			if !upgraded && newHash != tt.args.hash {
+				t.Errorf("VerifyAndUpgrade() = %s, expectation = %s", newHash, tt.args.hash)
+			}
+
+			if err = CompareHashAndPassword(newHash, tt.args.pass); err != nil {
+				t.Errorf("VerifyAndUpgrade() Failed to compare the new hash with the real password. %v.", err)
+			}
+
+			if rehash, _ := NeedsRehash(newHash, tt.args.target); rehash {
+				t.Errorf("VerifyAndUpgrade() returned a hash that still needs a rehash")
+			}
+		})
+	}
+}
+
+func TestCompareHashAndPasswordWithLimits(t *testing.T) {
+	type args struct {
+		hash   string
+		pass   []byte
+		limits *Limits
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name: "No limits",
+			args: args{
+				hash:   "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+				pass:   []byte("foo123"),
+				limits: nil,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Within limits",
+			args: args{
+				hash:   "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+				pass:   []byte("foo123"),
+				limits: &Limits{MaxMemory: 4096, MaxIterations: 3, MaxParallelism: 1, MaxKeyLength: 32},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Memory above limit",
+			args: args{
+				hash:   "$argon2id$v=19$m=4294967295,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+				pass:   []byte("foo123"),
+				limits: &Limits{MaxMemory: 64 * 1024},
+			},
+			wantErr:     true,
+			expectedErr: ErrLimitExceeded,
+		},
+		{
+			name: "Iterations above limit",
+			args: args{
+				hash:   "$argon2id$v=19$m=4096,t=4294967295,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+				pass:   []byte("foo123"),
+				limits: &Limits{MaxIterations: 10},
+			},
+			wantErr:     true,
+			expectedErr: ErrLimitExceeded,
+		},
+		{
+			name: "Parallelism above limit",
+			args: args{
+				hash:   "$argon2id$v=19$m=4096,t=3,p=255$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+				pass:   []byte("foo123"),
+				limits: &Limits{MaxParallelism: 4},
+			},
+			wantErr:     true,
+			expectedErr: ErrLimitExceeded,
+		},
+		{
+			name: "Key length above limit",
+			args: args{
+				hash:   "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+				pass:   []byte("foo123"),
+				limits: &Limits{MaxKeyLength: 16},
+			},
+			wantErr:     true,
+			expectedErr: ErrLimitExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CompareHashAndPasswordWithLimits(tt.args.hash, tt.args.pass, tt.args.limits)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CompareHashAndPasswordWithLimits() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, tt.expectedErr) {
+				t.Errorf("CompareHashAndPasswordWithLimits() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestGenerateFromPasswordWithAD(t *testing.T) {
+	params := &Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	hash, err := GenerateFromPasswordWithAD([]byte("foo123"), []byte("user-1"), params)
+	if err != nil {
+		t.Fatalf("GenerateFromPasswordWithAD() error = %v", err)
+	}
+
+	h, err := Parse(hash)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if string(h.Data) != "user-1" {
+		t.Errorf("Parse() data = %q, expectation = %q", h.Data, "user-1")
+	}
+	if h.String() != hash {
+		t.Errorf("Hash.String() = %s, expectation = %s", h.String(), hash)
+	}
+
+	tests := []struct {
+		name        string
+		pass        string
+		ad          []byte
+		expectedErr error
+	}{
+		{
+			name:        "Same associated data",
+			pass:        "foo123",
+			ad:          []byte("user-1"),
+			expectedErr: nil,
+		},
+		{
+			name:        "Hash moved to another account",
+			pass:        "foo123",
+			ad:          []byte("user-2"),
+			expectedErr: ErrPasswordNotMatch,
+		},
+		{
+			name:        "No associated data",
+			pass:        "foo123",
+			ad:          nil,
+			expectedErr: ErrPasswordNotMatch,
+		},
+		{
+			name:        "Wrong password",
+			pass:        "foo124",
+			ad:          []byte("user-1"),
+			expectedErr: ErrPasswordNotMatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := CompareHashAndPasswordWithAD(hash, []byte(tt.pass), tt.ad); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("CompareHashAndPasswordWithAD() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+		})
+	}
+
+	if err = CompareHashAndPassword(hash, []byte("foo123")); !errors.Is(err, ErrPasswordNotMatch) {
+		t.Errorf("CompareHashAndPassword() error = %v, expectation = %v", err, ErrPasswordNotMatch)
+	}
+}
+
+func TestGenerateFromPasswordContext(t *testing.T) {
+	heavy := &Params{Memory: 64 * 1024, Iterations: 1000, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := GenerateFromPasswordContext(canceled, []byte("foo123"), heavy); err != context.Canceled {
+		t.Errorf("GenerateFromPasswordContext() error = %v, expectation = %v", err, context.Canceled)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := GenerateFromPasswordContext(ctx, []byte("foo123"), heavy); err != context.DeadlineExceeded {
+		t.Errorf("GenerateFromPasswordContext() error = %v, expectation = %v", err, context.DeadlineExceeded)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("GenerateFromPasswordContext() returned after %v, long past the deadline", elapsed)
+	}
+
+	hash, err := GenerateFromPasswordContext(context.Background(), []byte("foo123"), testParams)
+	if err != nil {
+		t.Fatalf("GenerateFromPasswordContext() error = %v", err)
+	}
+	if err = CompareHashAndPassword(hash, []byte("foo123")); err != nil {
+		t.Errorf("CompareHashAndPassword() error = %v", err)
+	}
+}
+
+func TestCompareHashAndPasswordContext(t *testing.T) {
+	const hash = "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := CompareHashAndPasswordContext(ctx, hash, []byte("foo123")); err != nil {
+		t.Errorf("CompareHashAndPasswordContext() error = %v", err)
+	}
+	if err := CompareHashAndPasswordContext(ctx, hash, []byte("foo124")); !errors.Is(err, ErrPasswordNotMatch) {
+		t.Errorf("CompareHashAndPasswordContext() error = %v, expectation = %v", err, ErrPasswordNotMatch)
+	}
+
+	cancel()
+	if err := CompareHashAndPasswordContext(ctx, hash, []byte("foo123")); err != context.Canceled {
+		t.Errorf("CompareHashAndPasswordContext() error = %v, expectation = %v", err, context.Canceled)
+	}
+}
+
+// BenchmarkGenerateFromPassword compares the implementations of the compression function available on this CPU at the
+// recommended presets.
+func BenchmarkGenerateFromPassword(b *testing.B) {
+	impls := argon2core.Implementations()
+	defer argon2core.UseImplementation(impls[0])
+
+	for _, preset := range []Preset{PresetOWASP, PresetRFC9106Second} {
+		p := preset.Params
+		for _, impl := range impls {
+			b.Run(preset.Name+"/"+impl, func(b *testing.B) {
+				argon2core.UseImplementation(impl)
+				for i := 0; i < b.N; i++ {
+					if _, err := GenerateFromPassword([]byte("password"), &p); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
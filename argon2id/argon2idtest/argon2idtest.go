@@ -0,0 +1,71 @@
+// Package argon2idtest provides test doubles for the argon2id.PasswordHasher interface.
+//
+// Hasher produces real argon2 hashes at the lowest cost, for tests that need verifiable hashes without spending seconds
+// of CPU per call. FakeHasher does not hash at all and records its calls.
+// Neither must be used outside of tests.
+package argon2idtest
+
+import (
+	"github.com/gohango/argon2id/argon2id"
+)
+
+// Params are the low-cost parameters used by Hasher: the smallest memory and iterations argon2id accepts.
+var Params = argon2id.Params{
+	Variant:     argon2id.Argon2id,
+	Memory:      8,
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// Hasher is a argon2id.PasswordHasher producing real, verifiable argon2 hashes, but always with the cost of Params.
+// The variant, salt length and key length of the requested parameters are kept.
+type Hasher struct {
+	h *argon2id.Hasher
+}
+
+var _ argon2id.PasswordHasher = (*Hasher)(nil)
+
+// NewHasher returns a Hasher configured with the given options, such as peppers.
+// The parameters set by the options are replaced by low-cost ones.
+// Returns the hasher with nil error when successful. On failure, it returns nil with non-nil error.
+func NewHasher(opts ...argon2id.Option) (*Hasher, error) {
+	p := Params
+	opts = append(append([]argon2id.Option(nil), opts...), argon2id.WithParams(&p))
+
+	h, err := argon2id.NewHasher(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Hasher{h: h}, nil
+}
+
+// Generate hashes the password with the low-cost equivalent of p.
+func (h *Hasher) Generate(pass []byte, p *argon2id.Params) (string, error) {
+	return h.h.Generate(pass, lowCost(p))
+}
+
+// Compare verifies the password against the hash, which may have been generated by any argon2id.PasswordHasher.
+func (h *Hasher) Compare(hash string, pass []byte) error {
+	return h.h.Compare(hash, pass)
+}
+
+// NeedsRehash reports whether the hash is weaker than the low-cost equivalent of p.
+func (h *Hasher) NeedsRehash(hash string, p *argon2id.Params) (bool, error) {
+	return h.h.NeedsRehash(hash, lowCost(p))
+}
+
+// lowCost returns Params with the variant, salt length and key length of p, or nil when p is nil.
+func lowCost(p *argon2id.Params) *argon2id.Params {
+	if p == nil {
+		return nil
+	}
+
+	low := Params
+	low.Variant = p.Variant
+	low.SaltLength = p.SaltLength
+	low.KeyLength = p.KeyLength
+	return &low
+}
@@ -0,0 +1,64 @@
+package argon2idtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gohango/argon2id/argon2id"
+)
+
+func TestHasher(t *testing.T) {
+	h, err := NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	production := argon2id.DefaultPreset().Params
+
+	tests := []struct {
+		name       string
+		params     *argon2id.Params
+		wantPrefix string
+	}{
+		{
+			name:       "Default params",
+			params:     nil,
+			wantPrefix: "$argon2id$v=19$m=8,t=1,p=1$",
+		},
+		{
+			name:       "Production params",
+			params:     &production,
+			wantPrefix: "$argon2id$v=19$m=8,t=1,p=1$",
+		},
+		{
+			name:       "Another variant",
+			params:     &argon2id.Params{Variant: argon2id.Argon2i, Memory: 65536, Iterations: 3, Parallelism: 4, SaltLength: 16, KeyLength: 32},
+			wantPrefix: "$argon2i$v=19$m=8,t=1,p=1$",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := h.Generate([]byte("foo123"), tt.params)
+			if err != nil {
+				t.Fatalf("Hasher.Generate() error = %v", err)
+			}
+			if !strings.HasPrefix(hash, tt.wantPrefix) {
+				t.Errorf("Hasher.Generate() = %s, expected prefix %s", hash, tt.wantPrefix)
+			}
+
+			// The hashes are real ones, verified by the package itself.
+			if err = argon2id.CompareHashAndPassword(hash, []byte("foo123")); err != nil {
+				t.Errorf("CompareHashAndPassword() error = %v", err)
+			}
+			if err = h.Compare(hash, []byte("foo124")); err != argon2id.ErrPasswordNotMatch {
+				t.Errorf("Hasher.Compare() error = %v, expectation = %v", err, argon2id.ErrPasswordNotMatch)
+			}
+
+			rehash, err := h.NeedsRehash(hash, tt.params)
+			if err != nil || rehash {
+				t.Errorf("Hasher.NeedsRehash() = %v, %v, expectation = false", rehash, err)
+			}
+		})
+	}
+}
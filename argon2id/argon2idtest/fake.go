@@ -0,0 +1,115 @@
+package argon2idtest
+
+import (
+	"encoding/base64"
+	"sync"
+
+	"github.com/gohango/argon2id/argon2id"
+)
+
+// Call records a call to a FakeHasher.
+type Call struct {
+	// Method is the name of the method called: "Generate", "Compare" or "NeedsRehash".
+	Method string
+	// Hash is the hash given to Compare and NeedsRehash, or the one returned by Generate.
+	Hash string
+	// Password is a copy of the password given to Generate and Compare.
+	Password []byte
+	// Params is a copy of the parameters given to Generate and NeedsRehash, nil when none were given.
+	Params *argon2id.Params
+	// Err is the error returned by the call.
+	Err error
+}
+
+// FakeHasher is a argon2id.PasswordHasher that does not hash and records its calls.
+//
+// By default, Generate returns FakeHash(pass), Compare accepts a password only against its FakeHash and NeedsRehash
+// returns false. Each behavior can be replaced by setting the matching function field before use.
+// A FakeHasher is safe for concurrent use, and its zero value is ready to use.
+type FakeHasher struct {
+	GenerateFunc    func(pass []byte, p *argon2id.Params) (string, error)
+	CompareFunc     func(hash string, pass []byte) error
+	NeedsRehashFunc func(hash string, p *argon2id.Params) (bool, error)
+
+	mu    sync.Mutex
+	calls []Call
+}
+
+var _ argon2id.PasswordHasher = (*FakeHasher)(nil)
+
+// FakeHash returns the hash FakeHasher generates by default for the password. It is not a valid argon2 hash.
+func FakeHash(pass []byte) string {
+	return "$fake$" + base64.RawStdEncoding.EncodeToString(pass)
+}
+
+// Generate records the call and returns the result of GenerateFunc, or FakeHash(pass) when it is nil.
+func (f *FakeHasher) Generate(pass []byte, p *argon2id.Params) (string, error) {
+	var hash string
+	var err error
+	if f.GenerateFunc != nil {
+		hash, err = f.GenerateFunc(pass, p)
+	} else {
+		hash = FakeHash(pass)
+	}
+
+	f.record(Call{Method: "Generate", Hash: hash, Password: pass, Params: p, Err: err})
+	return hash, err
+}
+
+// Compare records the call and returns the result of CompareFunc or, when it is nil, nil if hash is FakeHash(pass) and
+// argon2id.ErrPasswordNotMatch otherwise.
+func (f *FakeHasher) Compare(hash string, pass []byte) error {
+	var err error
+	if f.CompareFunc != nil {
+		err = f.CompareFunc(hash, pass)
+	} else if hash != FakeHash(pass) {
+		err = argon2id.ErrPasswordNotMatch
+	}
+
+	f.record(Call{Method: "Compare", Hash: hash, Password: pass, Err: err})
+	return err
+}
+
+// NeedsRehash records the call and returns the result of NeedsRehashFunc, or false when it is nil.
+func (f *FakeHasher) NeedsRehash(hash string, p *argon2id.Params) (bool, error) {
+	var rehash bool
+	var err error
+	if f.NeedsRehashFunc != nil {
+		rehash, err = f.NeedsRehashFunc(hash, p)
+	}
+
+	f.record(Call{Method: "NeedsRehash", Hash: hash, Params: p, Err: err})
+	return rehash, err
+}
+
+// Calls returns the calls recorded so far, in order.
+func (f *FakeHasher) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]Call(nil), f.calls...)
+}
+
+// Reset forgets the calls recorded so far.
+func (f *FakeHasher) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = nil
+}
+
+// record appends a call, copying its arguments so that later changes by the caller are not seen.
+func (f *FakeHasher) record(c Call) {
+	if c.Password != nil {
+		c.Password = append([]byte(nil), c.Password...)
+	}
+	if c.Params != nil {
+		p := *c.Params
+		c.Params = &p
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, c)
+}
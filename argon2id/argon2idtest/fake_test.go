@@ -0,0 +1,73 @@
+package argon2idtest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/gohango/argon2id/argon2id"
+)
+
+func TestFakeHasher(t *testing.T) {
+	var f FakeHasher
+
+	p := &argon2id.Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	pass := []byte("foo123")
+
+	hash, err := f.Generate(pass, p)
+	if err != nil || hash != FakeHash(pass) {
+		t.Errorf("FakeHasher.Generate() = %s, %v, expectation = %s", hash, err, FakeHash(pass))
+	}
+	if err = f.Compare(hash, pass); err != nil {
+		t.Errorf("FakeHasher.Compare() error = %v", err)
+	}
+	if err = f.Compare(hash, []byte("foo124")); err != argon2id.ErrPasswordNotMatch {
+		t.Errorf("FakeHasher.Compare() error = %v, expectation = %v", err, argon2id.ErrPasswordNotMatch)
+	}
+	if rehash, err := f.NeedsRehash(hash, nil); err != nil || rehash {
+		t.Errorf("FakeHasher.NeedsRehash() = %v, %v, expectation = false", rehash, err)
+	}
+
+	// Changing the arguments after the calls does not change the records.
+	pass[0] = 'x'
+	p.Memory = 128
+
+	expected := []Call{
+		{Method: "Generate", Hash: hash, Password: []byte("foo123"), Params: &argon2id.Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}},
+		{Method: "Compare", Hash: hash, Password: []byte("foo123")},
+		{Method: "Compare", Hash: hash, Password: []byte("foo124"), Err: argon2id.ErrPasswordNotMatch},
+		{Method: "NeedsRehash", Hash: hash},
+	}
+	if calls := f.Calls(); !reflect.DeepEqual(calls, expected) {
+		t.Errorf("FakeHasher.Calls() = %+v, expectation = %+v", calls, expected)
+	}
+
+	f.Reset()
+	if calls := f.Calls(); len(calls) != 0 {
+		t.Errorf("FakeHasher.Calls() = %+v after Reset, expected none", calls)
+	}
+}
+
+func TestFakeHasherFuncs(t *testing.T) {
+	errFake := errors.New("fake error")
+	f := &FakeHasher{
+		GenerateFunc:    func([]byte, *argon2id.Params) (string, error) { return "", errFake },
+		CompareFunc:     func(string, []byte) error { return errFake },
+		NeedsRehashFunc: func(string, *argon2id.Params) (bool, error) { return true, nil },
+	}
+
+	var h argon2id.PasswordHasher = f
+	if _, err := h.Generate([]byte("foo123"), nil); err != errFake {
+		t.Errorf("FakeHasher.Generate() error = %v, expectation = %v", err, errFake)
+	}
+	if err := h.Compare("hash", []byte("foo123")); err != errFake {
+		t.Errorf("FakeHasher.Compare() error = %v, expectation = %v", err, errFake)
+	}
+	if rehash, err := h.NeedsRehash("hash", nil); err != nil || !rehash {
+		t.Errorf("FakeHasher.NeedsRehash() = %v, %v, expectation = true", rehash, err)
+	}
+
+	if calls := f.Calls(); len(calls) != 3 || calls[0].Err != errFake || calls[1].Err != errFake {
+		t.Errorf("FakeHasher.Calls() = %+v, expected the three calls with their errors", calls)
+	}
+}
@@ -0,0 +1,141 @@
+package argon2id
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	argon2core "github.com/gohango/argon2id/argon2id/internal/argon2"
+)
+
+// calibrationStartMemory is the memory, in KiB, of the first measured configuration.
+const calibrationStartMemory = 4096
+
+var (
+	ErrInvalidTarget = errors.New("the calibration target must be a positive duration")
+	ErrClockStalled  = errors.New("the clock did not advance while hashing")
+)
+
+// Calibrate benchmarks argon2id on the current machine and returns parameters whose hashing time reaches the target duration.
+// The memory is grown first, up to maxMemory KiB, and the iterations are only increased once the memory budget is used up.
+// The salt and key lengths are the ones used by GenerateFromPassword when no parameters are given.
+func Calibrate(target time.Duration, maxMemory uint32, parallelism uint8) (*Params, error) {
+	return defaultHasher.Calibrate(target, maxMemory, parallelism)
+}
+
+// Calibrate works like the Calibrate function, timing the hashes with the hasher's clock.
+// The salt and key lengths are the ones of the hasher's parameters.
+// Returns ErrClockStalled when two measurements in a row take no time, as with a frozen clock.
+func (h *Hasher) Calibrate(target time.Duration, maxMemory uint32, parallelism uint8) (*Params, error) {
+	if target <= 0 {
+		return nil, ErrInvalidTarget
+	}
+
+	p := &Params{
+		Variant:    Argon2id,
+		SaltLength: h.params.SaltLength,
+		KeyLength:  h.params.KeyLength,
+	}
+	p.Parallelism = parallelism
+	p.Iterations = 1
+	p.Memory = calibrationStartMemory
+	if p.Memory > maxMemory {
+		p.Memory = maxMemory
+	}
+	if floor := 8 * uint32(parallelism); p.Memory < floor {
+		p.Memory = floor
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if p.Memory > maxMemory {
+		return nil, ErrMemoryTooLow
+	}
+
+	// A single measurement taking no time is allowed, as a coarse clock may not see the smallest configuration.
+	var elapsed time.Duration
+	stalled := false
+	measure := func() error {
+		var err error
+		if elapsed, err = h.measure(p); err != nil {
+			return err
+		}
+		if elapsed > 0 {
+			stalled = false
+			return nil
+		}
+		if stalled {
+			return ErrClockStalled
+		}
+		stalled = true
+		elapsed = 1
+		return nil
+	}
+
+	if err := measure(); err != nil {
+		return nil, err
+	}
+
+	// Double the memory while the result stays below the target.
+	for p.Memory < maxMemory && 2*elapsed <= target {
+		if p.Memory > maxMemory/2 {
+			p.Memory = maxMemory
+		} else {
+			p.Memory *= 2
+		}
+		if err := measure(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Scale the memory linearly to close the remaining gap.
+	if p.Memory < maxMemory && elapsed < target {
+		memory := uint64(p.Memory) * uint64(target) / uint64(elapsed)
+		if memory > uint64(maxMemory) {
+			memory = uint64(maxMemory)
+		}
+		p.Memory = uint32(memory)
+		if err := measure(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Spend the rest of the budget on iterations, at most doubling them per measurement.
+	for elapsed < target {
+		iterations := uint64(p.Iterations) * uint64(target) / uint64(elapsed)
+		if iterations > 2*uint64(p.Iterations) {
+			iterations = 2 * uint64(p.Iterations)
+		}
+		if iterations <= uint64(p.Iterations) {
+			iterations = uint64(p.Iterations) + 1
+		}
+		if iterations > uint64(^uint32(0)) {
+			break
+		}
+		p.Iterations = uint32(iterations)
+		if err := measure(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// measure returns the time taken to hash a password with the given parameters.
+func (h *Hasher) measure(p *Params) (time.Duration, error) {
+	pass := []byte("calibration password")
+	hash := &Hash{
+		Version: argon2core.Version,
+		Params:  *p,
+		Salt:    make([]byte, p.SaltLength),
+	}
+
+	start := h.now()
+	// The calculation is timed with the hasher's threads, but not delayed by its memory budget.
+	if _, err := deriveKey(context.Background(), &engine{threads: h.engine.threads}, pass, nil, hash); err != nil {
+		return 0, err
+	}
+
+	return h.now().Sub(start), nil
+}
@@ -0,0 +1,151 @@
+package argon2id
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCalibrate(t *testing.T) {
+	type args struct {
+		target      time.Duration
+		maxMemory   uint32
+		parallelism uint8
+	}
+	tests := []struct {
+		name        string
+		args        args
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name: "Must reach the target within the memory budget",
+			args: args{
+				target:      50 * time.Millisecond,
+				maxMemory:   16 * 1024,
+				parallelism: 2,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Tiny memory budget",
+			args: args{
+				target:      10 * time.Millisecond,
+				maxMemory:   64,
+				parallelism: 1,
+			},
+			wantErr: false,
+		},
+		{
+			name: "Invalid target",
+			args: args{
+				target:      0,
+				maxMemory:   16 * 1024,
+				parallelism: 1,
+			},
+			wantErr:     true,
+			expectedErr: ErrInvalidTarget,
+		},
+		{
+			name: "Memory budget below 8 KiB per lane",
+			args: args{
+				target:      10 * time.Millisecond,
+				maxMemory:   16,
+				parallelism: 4,
+			},
+			wantErr:     true,
+			expectedErr: ErrMemoryTooLow,
+		},
+		{
+			name: "Invalid parallelism",
+			args: args{
+				target:      10 * time.Millisecond,
+				maxMemory:   16 * 1024,
+				parallelism: 0,
+			},
+			wantErr:     true,
+			expectedErr: ErrInvalidParallelism,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := Calibrate(tt.args.target, tt.args.maxMemory, tt.args.parallelism)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Calibrate() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("Calibrate() error = %v, expectation = %v", err, tt.expectedErr)
+				}
+				return
+			}
+
+			if err = p.Validate(); err != nil {
+				t.Errorf("Calibrate() returned invalid parameters %+v: %v", p, err)
+			}
+
+			if p.Memory > tt.args.maxMemory {
+				t.Errorf("Calibrate() memory = %d, budget = %d", p.Memory, tt.args.maxMemory)
+			}
+
+			if p.Parallelism != tt.args.parallelism {
+				t.Errorf("Calibrate() parallelism = %d, expectation = %d", p.Parallelism, tt.args.parallelism)
+			}
+		})
+	}
+}
+
+func TestHasherCalibrateClock(t *testing.T) {
+	// A clock running a thousand times faster makes every hash look a thousand times slower.
+	base := time.Now()
+	h, err := NewHasher(WithClock(func() time.Time {
+		return base.Add(time.Since(base) * 1000)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	target := 10 * time.Second
+	p, err := h.Calibrate(target, 16*1024, 1)
+	if err != nil {
+		t.Fatalf("Hasher.Calibrate() error = %v", err)
+	}
+
+	elapsed, err := defaultHasher.measure(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed >= target/100 {
+		t.Errorf("Hasher.Calibrate() params %+v take %v, expected the hasher's clock to be used", p, elapsed)
+	}
+}
+
+func TestHasherCalibrateStalledClock(t *testing.T) {
+	frozen := time.Now()
+	h, err := NewHasher(WithClock(func() time.Time { return frozen }))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = h.Calibrate(time.Second, 16*1024, 1); !errors.Is(err, ErrClockStalled) {
+		t.Errorf("Hasher.Calibrate() error = %v, expectation = %v", err, ErrClockStalled)
+	}
+
+	// A single measurement without elapsed time is tolerated, as on a coarse clock.
+	calls := 0
+	h, err = NewHasher(WithClock(func() time.Time {
+		if calls++; calls <= 2 {
+			return frozen
+		}
+		return frozen.Add(time.Since(frozen) * 1000)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = h.Calibrate(time.Second, 16*1024, 1); err != nil {
+		t.Errorf("Hasher.Calibrate() error = %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package argon2id
+
+import (
+	"strconv"
+)
+
+// Fields of an encoded hash reported by ParseError and UnsupportedError.
+const (
+	FieldAlgorithm = "algorithm"
+	FieldVersion   = "version"
+	FieldParams    = "params"
+	FieldSalt      = "salt"
+	FieldKey       = "key"
+)
+
+// ParseError is returned for malformed encoded hashes, such as corrupted or truncated ones.
+// It records the field that failed to decode and matches ErrInvalidHash with errors.Is, as well as the cause of the
+// failure, such as ErrSaltTooShort, when there is a more specific one.
+type ParseError struct {
+	// Field is the failing field: FieldAlgorithm, FieldVersion, FieldParams, FieldSalt or FieldKey.
+	Field string
+	// Err is the cause of the failure, ErrInvalidHash when there is no more specific one.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.Err == ErrInvalidHash {
+		return "the encoded hash has an invalid " + e.Field
+	}
+
+	return "the encoded hash has an invalid " + e.Field + ": " + e.Err.Error()
+}
+
+// Is reports whether target is ErrInvalidHash.
+func (e *ParseError) Is(target error) bool {
+	return target == ErrInvalidHash
+}
+
+// Unwrap returns the cause of the failure.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// parseError returns a ParseError for the field. ParseErrors and UnsupportedErrors are returned as they are.
+func parseError(field string, err error) error {
+	switch err.(type) {
+	case *ParseError, *UnsupportedError:
+		return err
+	}
+
+	return &ParseError{Field: field, Err: err}
+}
+
+// UnsupportedError is returned for well-formed hashes using an algorithm, version or option this package does not support.
+// It matches ErrIncompatibleVersion for unsupported argon2 variants and versions, and ErrUnknownAlgorithm for unsupported
+// normalizations and wrapped algorithms, with errors.Is.
+type UnsupportedError struct {
+	// Field is the unsupported field: FieldAlgorithm, FieldVersion or FieldParams.
+	Field string
+	// Value is the unsupported value, as encoded.
+	Value string
+	// Err is ErrIncompatibleVersion or ErrUnknownAlgorithm.
+	Err error
+}
+
+func (e *UnsupportedError) Error() string {
+	return e.Err.Error() + ": unsupported " + e.Field + " " + strconv.Quote(e.Value)
+}
+
+// Unwrap returns ErrIncompatibleVersion or ErrUnknownAlgorithm.
+func (e *UnsupportedError) Unwrap() error {
+	return e.Err
+}
+
+// LimitError is returned for hashes whose parameters are outside the verification policy set by Limits.
+// It matches ErrLimitExceeded with errors.Is.
+type LimitError struct {
+	// Param is the name of the parameter above its limit: "m", "t", "p", "keylen" or "wcost", or "i" for the
+	// iterations of PBKDF2 hashes and "cost" for the cost of bcrypt hashes. The memory of scrypt hashes is reported
+	// as "m", in KiB.
+	Param string
+	// Value is the value of the parameter in the hash.
+	Value uint64
+	// Max is the limit of the parameter.
+	Max uint64
+}
+
+func (e *LimitError) Error() string {
+	return ErrLimitExceeded.Error() + ": " + e.Param + "=" + strconv.FormatUint(e.Value, 10) +
+		" is above " + strconv.FormatUint(e.Max, 10)
+}
+
+// Is reports whether target is ErrLimitExceeded.
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
@@ -0,0 +1,154 @@
+package argon2id
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseErrors(t *testing.T) {
+	const salt = "c29tZXNhbHQ"
+	const key = "c29tZWhhc2g"
+
+	tests := []struct {
+		name          string
+		hash          string
+		wantField     string
+		expectedErr   error
+		wantMalformed bool
+	}{
+		{
+			name:          "Not a PHC string",
+			hash:          "argon2id",
+			wantField:     FieldAlgorithm,
+			expectedErr:   ErrInvalidHash,
+			wantMalformed: true,
+		},
+		{
+			name:          "Version with leading zero",
+			hash:          "$argon2id$v=019$m=64,t=1,p=1$" + salt + "$" + key,
+			wantField:     FieldVersion,
+			expectedErr:   ErrInvalidHash,
+			wantMalformed: true,
+		},
+		{
+			name:          "Cost that is not a number",
+			hash:          "$argon2id$v=19$m=abc,t=1,p=1$" + salt + "$" + key,
+			wantField:     FieldParams,
+			expectedErr:   ErrInvalidHash,
+			wantMalformed: true,
+		},
+		{
+			name:          "Memory below the minimum",
+			hash:          "$argon2id$v=19$m=4,t=1,p=1$" + salt + "$" + key,
+			wantField:     FieldParams,
+			expectedErr:   ErrMemoryTooLow,
+			wantMalformed: true,
+		},
+		{
+			name:          "Normalized wrapped hash",
+			hash:          "$argon2id$v=19$m=64,t=1,p=1,norm=opaquestring,wrap=md5$" + salt + "$" + key,
+			wantField:     FieldParams,
+			expectedErr:   ErrInvalidHash,
+			wantMalformed: true,
+		},
+		{
+			name:          "Salt outside of B64",
+			hash:          "$argon2id$v=19$m=64,t=1,p=1$c29t.ZXNhbHQ$" + key,
+			wantField:     FieldSalt,
+			expectedErr:   ErrInvalidHash,
+			wantMalformed: true,
+		},
+		{
+			name:          "Salt too short",
+			hash:          "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$" + key,
+			wantField:     FieldSalt,
+			expectedErr:   ErrSaltTooShort,
+			wantMalformed: true,
+		},
+		{
+			name:          "Missing key",
+			hash:          "$argon2id$v=19$m=64,t=1,p=1$" + salt,
+			wantField:     FieldKey,
+			expectedErr:   ErrInvalidHash,
+			wantMalformed: true,
+		},
+		{
+			name:          "Non-canonical key",
+			hash:          "$argon2id$v=19$m=64,t=1,p=1$" + salt + "$c29tZWhhc2h",
+			wantField:     FieldKey,
+			expectedErr:   ErrInvalidHash,
+			wantMalformed: true,
+		},
+		{
+			name:          "Unsupported variant",
+			hash:          "$argon2x$v=19$m=64,t=1,p=1$" + salt + "$" + key,
+			wantField:     FieldAlgorithm,
+			expectedErr:   ErrIncompatibleVersion,
+			wantMalformed: false,
+		},
+		{
+			name:          "Unsupported version",
+			hash:          "$argon2id$v=18$m=64,t=1,p=1$" + salt + "$" + key,
+			wantField:     FieldVersion,
+			expectedErr:   ErrIncompatibleVersion,
+			wantMalformed: false,
+		},
+		{
+			name:          "Unsupported normalization",
+			hash:          "$argon2id$v=19$m=64,t=1,p=1,norm=nfkc$" + salt + "$" + key,
+			wantField:     FieldParams,
+			expectedErr:   ErrUnknownAlgorithm,
+			wantMalformed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CompareHashAndPassword(tt.hash, []byte("password"))
+			if !errors.Is(err, tt.expectedErr) {
+				t.Errorf("CompareHashAndPassword() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+
+			// Malformed hashes are told apart from wrong passwords and unsupported hashes.
+			if errors.Is(err, ErrInvalidHash) != tt.wantMalformed {
+				t.Errorf("errors.Is(%v, ErrInvalidHash) = %v, expectation = %v", err, !tt.wantMalformed, tt.wantMalformed)
+			}
+			if errors.Is(err, ErrPasswordNotMatch) {
+				t.Errorf("CompareHashAndPassword() error = %v, expected no password mismatch", err)
+			}
+
+			var field string
+			var parseErr *ParseError
+			var unsupportedErr *UnsupportedError
+			switch {
+			case errors.As(err, &parseErr):
+				field = parseErr.Field
+			case errors.As(err, &unsupportedErr):
+				field = unsupportedErr.Field
+			}
+			if field != tt.wantField {
+				t.Errorf("CompareHashAndPassword() error = %v, field = %q, expectation = %q", err, field, tt.wantField)
+			}
+		})
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	hash, err := GenerateFromPassword([]byte("foo123"), &Params{Memory: 128, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = CompareHashAndPasswordWithLimits(hash, []byte("foo123"), &Limits{MaxMemory: 256, MaxIterations: 1})
+	if !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("CompareHashAndPasswordWithLimits() error = %v, expectation = %v", err, ErrLimitExceeded)
+	}
+
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Param != "t" || limitErr.Value != 2 || limitErr.Max != 1 {
+		t.Errorf("CompareHashAndPasswordWithLimits() error = %#v, expected the iterations above their limit", err)
+	}
+	if errors.Is(err, ErrInvalidHash) {
+		t.Errorf("errors.Is(%v, ErrInvalidHash) = true, expectation = false", err)
+	}
+}
@@ -0,0 +1,385 @@
+package argon2id
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"io"
+	"time"
+
+	argon2core "github.com/gohango/argon2id/argon2id/internal/argon2"
+)
+
+var (
+	ErrInvalidPepper       = errors.New("the pepper must have a non-empty identifier and secret")
+	ErrInvalidMemoryBudget = errors.New("the memory budget must be positive and the queue length not negative")
+	ErrInvalidOption       = errors.New("the hasher option is missing its value")
+)
+
+// PasswordHasher hashes and verifies passwords. It is implemented by Hasher, and by the test doubles of the
+// argon2idtest package, so that code depending on it can swap implementations without changing call sites.
+type PasswordHasher interface {
+	// Generate hashes the password with the given parameters, or the implementation's defaults when p is nil.
+	Generate(pass []byte, p *Params) (string, error)
+	// Compare returns nil when the password matches the hash, and ErrPasswordNotMatch when it does not.
+	Compare(hash string, pass []byte) error
+	// NeedsRehash reports whether the hash is weaker than the given parameters, or the implementation's defaults when p is nil.
+	NeedsRehash(hash string, p *Params) (bool, error)
+}
+
+var _ PasswordHasher = (*Hasher)(nil)
+
+// Hasher hashes and verifies passwords with a shared configuration.
+// The package-level functions use a Hasher with the default configuration.
+// A Hasher is safe for concurrent use once created.
+type Hasher struct {
+	// rand is the source of the salts.
+	rand io.Reader
+	// now returns the current time when measuring hashing durations.
+	now func() time.Time
+	// params are used when no parameters are given.
+	params *Params
+	// limits restrict the parameters of the hashes to verify, and are nil when unlimited.
+	limits *Limits
+	// peppers maps the identifier of every known pepper to its secret.
+	peppers map[string][]byte
+	// pepperID identifies the pepper used for new hashes.
+	pepperID string
+	// engine calculates the keys, within the memory budget and threads of the hasher.
+	engine engine
+	// normalization is applied to the passwords of new hashes, and is empty when they are hashed raw.
+	normalization string
+}
+
+// defaultHasher backs the package-level functions.
+var defaultHasher = newHasher()
+
+// Option configures a Hasher.
+type Option func(h *Hasher) error
+
+// newHasher returns a Hasher with the default configuration.
+func newHasher() *Hasher {
+	return &Hasher{
+		rand:    rand.Reader,
+		now:     time.Now,
+		params:  defaultParams(),
+		peppers: make(map[string][]byte),
+	}
+}
+
+// NewHasher returns a Hasher configured with the given options.
+// Without options, it behaves like the package-level functions.
+// Returns the hasher with nil error when successful. On failure, it returns nil with non-nil error.
+func NewHasher(opts ...Option) (*Hasher, error) {
+	h := newHasher()
+
+	for _, opt := range opts {
+		if err := opt(h); err != nil {
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// WithRand sets the source of the salts, crypto/rand.Reader by default.
+// The reader must be safe for concurrent use when the hasher is.
+func WithRand(r io.Reader) Option {
+	return func(h *Hasher) error {
+		if r == nil {
+			return ErrInvalidOption
+		}
+
+		h.rand = r
+		return nil
+	}
+}
+
+// WithClock sets the clock used to measure hashing durations, time.Now by default.
+func WithClock(now func() time.Time) Option {
+	return func(h *Hasher) error {
+		if now == nil {
+			return ErrInvalidOption
+		}
+
+		h.now = now
+		return nil
+	}
+}
+
+// WithParams sets the parameters used when none are given, the ones of DefaultPreset by default.
+// The parameters are copied and must be valid.
+func WithParams(p *Params) Option {
+	return func(h *Hasher) error {
+		if p == nil {
+			return ErrInvalidOption
+		}
+		if err := p.Validate(); err != nil {
+			return err
+		}
+
+		params := *p
+		h.params = &params
+		return nil
+	}
+}
+
+// WithLimits restricts the parameters of the hashes the hasher verifies, see CompareHashAndPasswordWithLimits.
+// The limits are copied. Hashes are not limited by default.
+func WithLimits(l *Limits) Option {
+	return func(h *Hasher) error {
+		if l == nil {
+			return ErrInvalidOption
+		}
+
+		limits := *l
+		h.limits = &limits
+		return nil
+	}
+}
+
+// WithPepper adds a pepper, a server-side secret mixed into every key as the argon2 secret value K.
+// The pepper is recorded in the hashes by its identifier, using the keyid parameter, but its secret never is.
+// The last pepper added is used for new hashes, while all of them are accepted when verifying, so that peppers can be rotated.
+func WithPepper(id string, secret []byte) Option {
+	return func(h *Hasher) error {
+		if id == "" || len(secret) == 0 {
+			return ErrInvalidPepper
+		}
+
+		h.peppers[id] = append([]byte(nil), secret...)
+		h.pepperID = id
+		return nil
+	}
+}
+
+// WithMemoryBudget bounds the memory used by concurrent hashing and verification calls to budget KiB.
+// Each call is admitted once its memory cost fits in what is left of the budget, in arrival order.
+// Up to maxQueue calls wait for memory to be released; further calls fail right away with ErrOverloaded, as do calls
+// costing more than the whole budget.
+func WithMemoryBudget(budget uint64, maxQueue int) Option {
+	return func(h *Hasher) error {
+		if budget == 0 || maxQueue < 0 {
+			return ErrInvalidMemoryBudget
+		}
+
+		h.engine.limiter = newLimiter(budget, maxQueue)
+		return nil
+	}
+}
+
+// WithArenaPool reuses the argon2 memory across hashing and verification calls, instead of allocating it on every call,
+// which reduces the garbage collection load and the swings of the memory in use.
+// Up to size idle arenas are kept for reuse, and they are zeroed after every use.
+// Combine it with WithMemoryBudget to also bound the memory of the arenas in use.
+func WithArenaPool(size int) Option {
+	return func(h *Hasher) error {
+		if size < 1 {
+			return ErrInvalidOption
+		}
+
+		h.engine.arenas = newArenaPool(size)
+		return nil
+	}
+}
+
+// WithThreads bounds the number of lanes each hashing and verification call computes at once to n.
+// By default, every lane of a hash runs in its own goroutine. The hashes are the same either way.
+func WithThreads(n int) Option {
+	return func(h *Hasher) error {
+		if n < 1 {
+			return ErrInvalidOption
+		}
+
+		h.engine.threads = n
+		return nil
+	}
+}
+
+// Generate works like GenerateFromPassword, using the hasher's parameters when p is nil and mixing the current pepper,
+// if any, into the key.
+func (h *Hasher) Generate(pass []byte, p *Params) (string, error) {
+	return h.generate(context.Background(), pass, nil, p, nil)
+}
+
+// GenerateWithAD works like GenerateFromPasswordWithAD, using the hasher's parameters when p is nil and mixing the
+// current pepper, if any, into the key.
+func (h *Hasher) GenerateWithAD(pass, ad []byte, p *Params) (string, error) {
+	return h.generate(context.Background(), pass, ad, p, nil)
+}
+
+// GenerateContext works like GenerateFromPasswordContext, using the hasher's parameters when p is nil and mixing the
+// current pepper, if any, into the key.
+func (h *Hasher) GenerateContext(ctx context.Context, pass []byte, p *Params) (string, error) {
+	return h.generate(ctx, pass, nil, p, nil)
+}
+
+// generate hashes the password and associated data with a random salt, mixing in the current pepper, if any.
+// When w is not nil, pass is the legacy hash it describes.
+func (h *Hasher) generate(ctx context.Context, pass, ad []byte, p *Params, w *Wrapping) (string, error) {
+	if p == nil {
+		p = h.params
+	}
+
+	if err := p.Validate(); err != nil {
+		return "", err
+	}
+
+	// Wrapped legacy hashes were computed from the raw password.
+	var normalization string
+	if w == nil {
+		var err error
+		normalization = h.normalization
+		if pass, err = normalize(normalization, pass); err != nil {
+			return "", err
+		}
+	}
+
+	// Generate the salt.
+	unencodedSalt := make([]byte, p.SaltLength)
+
+	_, err := io.ReadFull(h.rand, unencodedSalt)
+	if err != nil {
+		return "", err
+	}
+
+	// Generate the hashed password.
+	hash := &Hash{
+		Version:       argon2core.Version,
+		Params:        *p,
+		Data:          ad,
+		Normalization: normalization,
+		Wrap:          w,
+		Salt:          unencodedSalt,
+	}
+	var secret []byte
+	if h.pepperID != "" {
+		hash.KeyID = []byte(h.pepperID)
+		secret = h.peppers[h.pepperID]
+	}
+
+	hash.Key, err = deriveKey(ctx, &h.engine, pass, secret, hash)
+	if err != nil {
+		return "", err
+	}
+
+	return hash.String(), nil
+}
+
+// Compare works like CompareHashAndPassword, within the hasher's limits and using the pepper recorded in the hash.
+// Returns ErrUnknownKeyID when the hash was generated with a pepper the hasher does not know.
+func (h *Hasher) Compare(hash string, pass []byte) error {
+	return h.compare(context.Background(), hash, pass, nil, h.limits)
+}
+
+// CompareWithAD works like CompareHashAndPasswordWithAD, within the hasher's limits and using the pepper recorded in the hash.
+// Returns ErrUnknownKeyID when the hash was generated with a pepper the hasher does not know.
+func (h *Hasher) CompareWithAD(hash string, pass, ad []byte) error {
+	return h.compare(context.Background(), hash, pass, ad, h.limits)
+}
+
+// CompareContext works like CompareHashAndPasswordContext, within the hasher's limits and using the pepper recorded in the hash.
+// Returns ErrUnknownKeyID when the hash was generated with a pepper the hasher does not know.
+func (h *Hasher) CompareContext(ctx context.Context, hash string, pass []byte) error {
+	return h.compare(ctx, hash, pass, nil, h.limits)
+}
+
+// compare compares the hash with the password and associated data, within the given limits.
+func (h *Hasher) compare(ctx context.Context, hash string, pass, ad []byte, l *Limits) error {
+	decoded, err := decodeHash(hash)
+	if err != nil {
+		return err
+	}
+
+	if err = l.check(decoded); err != nil {
+		return err
+	}
+
+	var secret []byte
+	if len(decoded.KeyID) > 0 {
+		var ok bool
+		if secret, ok = h.peppers[string(decoded.KeyID)]; !ok {
+			return ErrUnknownKeyID
+		}
+	}
+
+	return compareHash(ctx, &h.engine, decoded, pass, secret, ad)
+}
+
+// CompareNoUser runs a full-cost verification of the password against a dummy hash with the hasher's parameters and
+// current pepper, and returns ErrPasswordNotMatch.
+// Call it when the user to authenticate does not exist, so that the response takes about as long as for an existing user
+// and does not reveal which accounts exist. Hashes generated with explicit parameters may take a different time to verify.
+// The errors Compare returns for any hash, such as ErrOverloaded when the memory budget is exhausted, are returned as well.
+func (h *Hasher) CompareNoUser(pass []byte) error {
+	dummy := h.dummyHash()
+
+	// The dummy hash matches no password, but the other errors are the ones of a real comparison.
+	err := compareHash(context.Background(), &h.engine, dummy, pass, h.peppers[string(dummy.KeyID)], nil)
+	if err == nil {
+		return ErrPasswordNotMatch
+	}
+
+	return err
+}
+
+// dummyHash returns the hash CompareNoUser verifies, which has the hasher's parameters, normalization and current pepper.
+func (h *Hasher) dummyHash() *Hash {
+	return &Hash{
+		Version:       argon2core.Version,
+		Params:        *h.params,
+		KeyID:         []byte(h.pepperID),
+		Normalization: h.normalization,
+		Salt:          make([]byte, h.params.SaltLength),
+		Key:           make([]byte, h.params.KeyLength),
+	}
+}
+
+// NeedsRehash works like the NeedsRehash function, using the hasher's parameters when p is nil.
+// It also reports hashes that do not use the current pepper, or the normalization when the hasher applies one.
+func (h *Hasher) NeedsRehash(hash string, p *Params) (bool, error) {
+	if p == nil {
+		p = h.params
+	}
+
+	decoded, err := decodeHash(hash)
+	if err != nil {
+		return false, err
+	}
+
+	return decoded.Version != argon2core.Version ||
+		decoded.Params.Variant != p.Variant ||
+		decoded.Params.Memory < p.Memory ||
+		decoded.Params.Iterations < p.Iterations ||
+		decoded.Params.Parallelism < p.Parallelism ||
+		decoded.Params.SaltLength < p.SaltLength ||
+		decoded.Params.KeyLength < p.KeyLength ||
+		decoded.Wrap != nil ||
+		(h.normalization != "" && decoded.Normalization != h.normalization) ||
+		!bytes.Equal(decoded.KeyID, []byte(h.pepperID)), nil
+}
+
+// VerifyAndUpgrade works like the VerifyAndUpgrade function, using the hasher's parameters when target is nil.
+// Hashes that do not use the current pepper or normalization, or wrap a legacy hash, are upgraded as well.
+func (h *Hasher) VerifyAndUpgrade(hash string, pass []byte, target *Params) (newHash string, upgraded bool, err error) {
+	if err = h.Compare(hash, pass); err != nil {
+		return "", false, err
+	}
+
+	rehash, err := h.NeedsRehash(hash, target)
+	if err != nil {
+		return "", false, err
+	}
+
+	if !rehash {
+		return hash, false, nil
+	}
+
+	newHash, err = h.Generate(pass, target)
+	if err != nil {
+		return "", false, err
+	}
+
+	return newHash, true, nil
+}
@@ -0,0 +1,359 @@
+package argon2id
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var testParams = &Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestNewHasher(t *testing.T) {
+	tests := []struct {
+		name        string
+		opts        []Option
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name:    "No options",
+			opts:    nil,
+			wantErr: false,
+		},
+		{
+			name:    "Pepper",
+			opts:    []Option{WithPepper("k1", []byte("secret"))},
+			wantErr: false,
+		},
+		{
+			name:        "Pepper without identifier",
+			opts:        []Option{WithPepper("", []byte("secret"))},
+			wantErr:     true,
+			expectedErr: ErrInvalidPepper,
+		},
+		{
+			name:        "Pepper without secret",
+			opts:        []Option{WithPepper("k1", nil)},
+			wantErr:     true,
+			expectedErr: ErrInvalidPepper,
+		},
+		{
+			name:        "Nil random source",
+			opts:        []Option{WithRand(nil)},
+			wantErr:     true,
+			expectedErr: ErrInvalidOption,
+		},
+		{
+			name:        "Nil clock",
+			opts:        []Option{WithClock(nil)},
+			wantErr:     true,
+			expectedErr: ErrInvalidOption,
+		},
+		{
+			name:        "Nil params",
+			opts:        []Option{WithParams(nil)},
+			wantErr:     true,
+			expectedErr: ErrInvalidOption,
+		},
+		{
+			name:        "Invalid params",
+			opts:        []Option{WithParams(&Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 4, KeyLength: 32})},
+			wantErr:     true,
+			expectedErr: ErrSaltTooShort,
+		},
+		{
+			name:        "No threads",
+			opts:        []Option{WithThreads(0)},
+			wantErr:     true,
+			expectedErr: ErrInvalidOption,
+		},
+		{
+			name:        "Nil limits",
+			opts:        []Option{WithLimits(nil)},
+			wantErr:     true,
+			expectedErr: ErrInvalidOption,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewHasher(tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("NewHasher() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, tt.expectedErr) {
+				t.Errorf("NewHasher() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
+
+func TestHasherPepper(t *testing.T) {
+	old, err := NewHasher(WithPepper("k1", []byte("first secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := NewHasher(WithPepper("k1", []byte("first secret")), WithPepper("k2", []byte("second secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaked, err := NewHasher(WithPepper("k1", []byte("wrong secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := old.Generate([]byte("foo123"), testParams)
+	if err != nil {
+		t.Fatalf("Hasher.Generate() error = %v", err)
+	}
+	if !strings.Contains(hash, ",keyid=azE$") {
+		t.Errorf("Hasher.Generate() = %s, expected the keyid of k1", hash)
+	}
+
+	unpeppered, err := plain.Generate([]byte("foo123"), testParams)
+	if err != nil {
+		t.Fatalf("Hasher.Generate() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		hasher      *Hasher
+		hash        string
+		pass        string
+		expectedErr error
+		wantRehash  bool
+	}{
+		{
+			name:        "Same pepper",
+			hasher:      old,
+			hash:        hash,
+			pass:        "foo123",
+			expectedErr: nil,
+			wantRehash:  false,
+		},
+		{
+			name:        "Rotated pepper still verifies the old hash",
+			hasher:      rotated,
+			hash:        hash,
+			pass:        "foo123",
+			expectedErr: nil,
+			wantRehash:  true,
+		},
+		{
+			name:        "Wrong password",
+			hasher:      old,
+			hash:        hash,
+			pass:        "foo124",
+			expectedErr: ErrPasswordNotMatch,
+			wantRehash:  false,
+		},
+		{
+			name:        "Wrong pepper secret",
+			hasher:      leaked,
+			hash:        hash,
+			pass:        "foo123",
+			expectedErr: ErrPasswordNotMatch,
+			wantRehash:  false,
+		},
+		{
+			name:        "Hasher without the pepper",
+			hasher:      plain,
+			hash:        hash,
+			pass:        "foo123",
+			expectedErr: ErrUnknownKeyID,
+			wantRehash:  true,
+		},
+		{
+			name:        "Unpeppered hash",
+			hasher:      old,
+			hash:        unpeppered,
+			pass:        "foo123",
+			expectedErr: nil,
+			wantRehash:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.hasher.Compare(tt.hash, []byte(tt.pass)); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Hasher.Compare() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+
+			rehash, err := tt.hasher.NeedsRehash(tt.hash, testParams)
+			if err != nil {
+				t.Fatalf("Hasher.NeedsRehash() error = %v", err)
+			}
+			if rehash != tt.wantRehash {
+				t.Errorf("Hasher.NeedsRehash() = %v, expectation = %v", rehash, tt.wantRehash)
+			}
+		})
+	}
+
+	if err = CompareHashAndPassword(hash, []byte("foo123")); !errors.Is(err, ErrUnknownKeyID) {
+		t.Errorf("CompareHashAndPassword() error = %v, expectation = %v", err, ErrUnknownKeyID)
+	}
+}
+
+func TestHasherWithAD(t *testing.T) {
+	h, err := NewHasher(WithPepper("k1", []byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := h.GenerateWithAD([]byte("foo123"), []byte("tenant-1"), testParams)
+	if err != nil {
+		t.Fatalf("Hasher.GenerateWithAD() error = %v", err)
+	}
+	if !strings.Contains(hash, ",keyid=azE,data=dGVuYW50LTE$") {
+		t.Errorf("Hasher.GenerateWithAD() = %s, expected the keyid and data parameters", hash)
+	}
+
+	if err = h.CompareWithAD(hash, []byte("foo123"), []byte("tenant-1")); err != nil {
+		t.Errorf("Hasher.CompareWithAD() error = %v", err)
+	}
+	if err = h.CompareWithAD(hash, []byte("foo123"), []byte("tenant-2")); !errors.Is(err, ErrPasswordNotMatch) {
+		t.Errorf("Hasher.CompareWithAD() error = %v, expectation = %v", err, ErrPasswordNotMatch)
+	}
+	if err = h.Compare(hash, []byte("foo123")); !errors.Is(err, ErrPasswordNotMatch) {
+		t.Errorf("Hasher.Compare() error = %v, expectation = %v", err, ErrPasswordNotMatch)
+	}
+}
+
+func TestHasherOptions(t *testing.T) {
+	salt := bytes.Repeat([]byte{0x2a}, 16)
+	h, err := NewHasher(
+		WithRand(bytes.NewReader(append(append([]byte(nil), salt...), salt...))),
+		WithParams(testParams),
+		WithLimits(&Limits{MaxMemory: 64}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The same salt and the hasher's params make both hashes identical.
+	first, err := h.Generate([]byte("foo123"), nil)
+	if err != nil {
+		t.Fatalf("Hasher.Generate() error = %v", err)
+	}
+	second, err := h.Generate([]byte("foo123"), nil)
+	if err != nil {
+		t.Fatalf("Hasher.Generate() error = %v", err)
+	}
+	if first != second {
+		t.Errorf("Hasher.Generate() = %s and %s, expected the same hash", first, second)
+	}
+	if !strings.HasPrefix(first, "$argon2id$v=19$m=64,t=1,p=1$KioqKioqKioqKioqKioqKg$") {
+		t.Errorf("Hasher.Generate() = %s, expected the hasher's salt and params", first)
+	}
+
+	// The random source is exhausted.
+	if _, err = h.Generate([]byte("foo123"), nil); err == nil {
+		t.Errorf("Hasher.Generate() error = nil, expected the error of the random source")
+	}
+
+	if rehash, err := h.NeedsRehash(first, nil); err != nil || rehash {
+		t.Errorf("Hasher.NeedsRehash() = %v, %v, expectation = false", rehash, err)
+	}
+
+	if err = h.Compare(first, []byte("foo123")); err != nil {
+		t.Errorf("Hasher.Compare() error = %v", err)
+	}
+
+	stronger, err := GenerateFromPassword([]byte("foo123"), &Params{Memory: 128, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = h.Compare(stronger, []byte("foo123")); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Hasher.Compare() error = %v, expectation = %v", err, ErrLimitExceeded)
+	}
+}
+
+func TestHasherCompareNoUser(t *testing.T) {
+	p := &Params{Memory: 4096, Iterations: 4, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	h, err := NewHasher(WithParams(p), WithPepper("k1", []byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pass := range []string{"foo123", ""} {
+		if err = h.CompareNoUser([]byte(pass)); !errors.Is(err, ErrPasswordNotMatch) {
+			t.Errorf("Hasher.CompareNoUser() error = %v, expectation = %v", err, ErrPasswordNotMatch)
+		}
+	}
+
+	// The dummy verification does the same work as a real one.
+	dummy := h.dummyHash()
+	if dummy.Params != *p || string(dummy.KeyID) != "k1" || len(dummy.Salt) != 16 || len(dummy.Key) != 32 {
+		t.Errorf("Hasher.CompareNoUser() verifies %+v, expected the hasher's parameters and pepper", dummy)
+	}
+
+	if err = CompareNoUser([]byte("foo123")); !errors.Is(err, ErrPasswordNotMatch) {
+		t.Errorf("CompareNoUser() error = %v, expectation = %v", err, ErrPasswordNotMatch)
+	}
+}
+
+func TestHasherCompareNoUserMemoryBudget(t *testing.T) {
+	h, err := NewHasher(WithParams(testParams), WithMemoryBudget(uint64(testParams.Memory), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := h.Generate([]byte("foo123"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Once the budget is exhausted, a missing user fails like an existing one.
+	if err = h.engine.limiter.acquire(context.Background(), uint64(testParams.Memory)); err != nil {
+		t.Fatal(err)
+	}
+	defer h.engine.limiter.release(uint64(testParams.Memory))
+
+	if err = h.Compare(hash, []byte("foo123")); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Hasher.Compare() error = %v, expectation = %v", err, ErrOverloaded)
+	}
+	if err = h.CompareNoUser([]byte("foo123")); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Hasher.CompareNoUser() error = %v, expectation = %v", err, ErrOverloaded)
+	}
+}
+
+func TestHasherThreads(t *testing.T) {
+	p := &Params{Memory: 256, Iterations: 2, Parallelism: 4, SaltLength: 16, KeyLength: 32}
+	salt := bytes.Repeat([]byte{0x2a}, 16)
+
+	// Hashes are the same whatever the number of threads computing the lanes.
+	expected, err := NewHasher(WithRand(bytes.NewReader(salt)), WithThreads(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := expected.Generate([]byte("foo123"), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, threads := range []int{2, 3, 4, 16} {
+		h, err := NewHasher(WithRand(bytes.NewReader(salt)), WithThreads(threads))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		hash, err := h.Generate([]byte("foo123"), p)
+		if err != nil {
+			t.Fatalf("Hasher.Generate() error = %v", err)
+		}
+		if hash != want {
+			t.Errorf("Hasher.Generate() with %d threads = %s, expectation = %s", threads, hash, want)
+		}
+
+		if err = h.Compare(want, []byte("foo123")); err != nil {
+			t.Errorf("Hasher.Compare() with %d threads error = %v", threads, err)
+		}
+	}
+}
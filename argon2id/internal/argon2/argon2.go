@@ -0,0 +1,349 @@
+// Copyright 2017 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package argon2 implements the Argon2 key derivation function as specified
+// by RFC 9106, for all of its variants, versions and inputs.
+//
+// It is derived from golang.org/x/crypto/argon2, which only exports Argon2i and
+// Argon2id without the secret K and the associated data X, so that the argon2id
+// package can support them as well. Its output is identical to the one of
+// golang.org/x/crypto/argon2 for the inputs both support.
+package argon2
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+)
+
+// The Argon2 versions implemented by this package. Version is the current one.
+const (
+	Version10 = 0x10
+	Version13 = 0x13
+	Version   = Version13
+)
+
+// Mode identifies an Argon2 variant.
+type Mode int
+
+// The Argon2 variants, numbered as in the type field of the Argon2 H0 input.
+const (
+	Argon2d Mode = iota
+	Argon2i
+	Argon2id
+)
+
+// MaxLanes is the maximum degree of parallelism allowed by RFC 9106.
+const MaxLanes = 1<<24 - 1
+
+// Input stores the inputs of Argon2, named after RFC 9106: the password P,
+// the salt S, the secret K, the associated data X, the number of passes t,
+// the memory size m in KiB, the degree of parallelism p and the tag length T.
+// Version defaults to the current one when zero.
+//
+// Threads, Arena and Pool are not inputs of Argon2 and do not change the key.
+// Threads bounds the number of lanes computed concurrently, and defaults to
+// all of them when zero. Arena provides the memory, which is allocated when
+// the arena is nil or too small. Pool runs the lanes, which run on their own
+// goroutines when it is nil.
+type Input struct {
+	Mode     Mode
+	Version  uint32
+	Password []byte
+	Salt     []byte
+	Secret   []byte
+	Data     []byte
+	Time     uint32
+	Memory   uint32
+	Lanes    uint32
+	KeyLen   uint32
+	Threads  int
+	Arena    *Arena
+	Pool     *Pool
+}
+
+// Arena is memory reused across calls to Derive, saving the allocation of
+// memory KiB on each call. It is zeroed after every use. An arena must not be
+// used by concurrent calls.
+type Arena struct {
+	blocks []block
+}
+
+// NewArena returns an arena holding memory KiB.
+func NewArena(memory uint32) *Arena {
+	return &Arena{blocks: make([]block, memory)}
+}
+
+// Memory returns the size of the arena in KiB.
+func (a *Arena) Memory() uint32 {
+	return uint32(len(a.blocks))
+}
+
+// clear zeroes the first n blocks of the arena.
+func (a *Arena) clear(n uint32) {
+	blocks := a.blocks[:n]
+	for i := range blocks {
+		blocks[i] = block{}
+	}
+}
+
+// Key derives a key of length keyLen from the password, salt, secret and
+// associated data using the given Argon2 mode. The number of passes and the
+// parallelism degree must be greater than zero.
+func Key(mode Mode, password, salt, secret, data []byte, time, memory, lanes, keyLen uint32) []byte {
+	key, _ := Derive(context.Background(), &Input{
+		Mode:     mode,
+		Password: password,
+		Salt:     salt,
+		Secret:   secret,
+		Data:     data,
+		Time:     time,
+		Memory:   memory,
+		Lanes:    lanes,
+		KeyLen:   keyLen,
+	})
+	return key
+}
+
+// Derive derives a key from the given inputs. The version must be Version10
+// or Version13, the number of passes must be greater than zero, and the
+// degree of parallelism must be between 1 and MaxLanes.
+//
+// The context is checked at every synchronization point, four times per pass.
+// Once it is done, the computation stops and Derive returns the context error,
+// leaving the memory to the garbage collector.
+func Derive(ctx context.Context, in *Input) ([]byte, error) {
+	version := in.Version
+	if version == 0 {
+		version = Version
+	}
+	if version != Version10 && version != Version13 {
+		panic("argon2: unsupported version")
+	}
+	if in.Time < 1 {
+		panic("argon2: number of rounds too small")
+	}
+	if in.Lanes < 1 {
+		panic("argon2: parallelism degree too low")
+	}
+	if in.Lanes > MaxLanes {
+		panic("argon2: parallelism degree too high")
+	}
+	threads := in.Lanes
+	h0 := initHash(in.Password, in.Salt, in.Secret, in.Data, in.Time, in.Memory, threads, in.KeyLen, version, in.Mode)
+
+	memory := in.Memory / (syncPoints * threads) * (syncPoints * threads)
+	if memory < 2*syncPoints*threads {
+		memory = 2 * syncPoints * threads
+	}
+	var B []block
+	if in.Arena != nil && in.Arena.Memory() >= memory {
+		// The blocks derive from the password: wipe them as soon as the key
+		// is extracted, which also leaves the arena zeroed for its next use.
+		B = in.Arena.blocks[:memory]
+		defer in.Arena.clear(memory)
+	} else {
+		B = make([]block, memory)
+	}
+	initBlocks(&h0, B, threads)
+	workers := in.Threads
+	if workers <= 0 || workers > int(threads) {
+		workers = int(threads)
+	}
+	if err := processBlocks(ctx, B, in.Time, memory, threads, version, in.Mode, workers, in.Pool); err != nil {
+		return nil, err
+	}
+	return extractKey(B, memory, threads, in.KeyLen), nil
+}
+
+const (
+	blockLength = 128
+	syncPoints  = 4
+)
+
+type block [blockLength]uint64
+
+func initHash(password, salt, key, data []byte, time, memory, threads, keyLen, version uint32, mode Mode) [blake2b.Size + 8]byte {
+	var (
+		h0     [blake2b.Size + 8]byte
+		params [24]byte
+		tmp    [4]byte
+	)
+
+	b2, _ := blake2b.New512(nil)
+	binary.LittleEndian.PutUint32(params[0:4], threads)
+	binary.LittleEndian.PutUint32(params[4:8], keyLen)
+	binary.LittleEndian.PutUint32(params[8:12], memory)
+	binary.LittleEndian.PutUint32(params[12:16], time)
+	binary.LittleEndian.PutUint32(params[16:20], version)
+	binary.LittleEndian.PutUint32(params[20:24], uint32(mode))
+	b2.Write(params[:])
+	binary.LittleEndian.PutUint32(tmp[:], uint32(len(password)))
+	b2.Write(tmp[:])
+	b2.Write(password)
+	binary.LittleEndian.PutUint32(tmp[:], uint32(len(salt)))
+	b2.Write(tmp[:])
+	b2.Write(salt)
+	binary.LittleEndian.PutUint32(tmp[:], uint32(len(key)))
+	b2.Write(tmp[:])
+	b2.Write(key)
+	binary.LittleEndian.PutUint32(tmp[:], uint32(len(data)))
+	b2.Write(tmp[:])
+	b2.Write(data)
+	b2.Sum(h0[:0])
+	return h0
+}
+
+// initBlocks fills the first two blocks of each lane of the zeroed memory B.
+func initBlocks(h0 *[blake2b.Size + 8]byte, B []block, threads uint32) {
+	var block0 [1024]byte
+	memory := uint32(len(B))
+	for lane := uint32(0); lane < threads; lane++ {
+		j := lane * (memory / threads)
+		binary.LittleEndian.PutUint32(h0[blake2b.Size+4:], lane)
+
+		binary.LittleEndian.PutUint32(h0[blake2b.Size:], 0)
+		blake2bHash(block0[:], h0[:])
+		for i := range B[j+0] {
+			B[j+0][i] = binary.LittleEndian.Uint64(block0[i*8:])
+		}
+
+		binary.LittleEndian.PutUint32(h0[blake2b.Size:], 1)
+		blake2bHash(block0[:], h0[:])
+		for i := range B[j+1] {
+			B[j+1][i] = binary.LittleEndian.Uint64(block0[i*8:])
+		}
+	}
+}
+
+// processBlocks fills the memory, computing up to workers lanes at once on the pool.
+func processBlocks(ctx context.Context, B []block, time, memory, threads, version uint32, mode Mode, workers int, pool *Pool) error {
+	lanes := memory / threads
+	segments := lanes / syncPoints
+
+	processSegment := func(n, slice, lane uint32) {
+		var addresses, in, zero block
+		if mode == Argon2i || (mode == Argon2id && n == 0 && slice < syncPoints/2) {
+			in[0] = uint64(n)
+			in[1] = uint64(lane)
+			in[2] = uint64(slice)
+			in[3] = uint64(memory)
+			in[4] = uint64(time)
+			in[5] = uint64(mode)
+		}
+
+		index := uint32(0)
+		if n == 0 && slice == 0 {
+			index = 2 // we have already generated the first two blocks
+			if mode == Argon2i || mode == Argon2id {
+				in[6]++
+				processBlock(&addresses, &in, &zero)
+				processBlock(&addresses, &addresses, &zero)
+			}
+		}
+
+		offset := lane*lanes + slice*segments + index
+		var random uint64
+		for index < segments {
+			prev := offset - 1
+			if index == 0 && slice == 0 {
+				prev += lanes // last block in lane
+			}
+			if mode == Argon2i || (mode == Argon2id && n == 0 && slice < syncPoints/2) {
+				if index%blockLength == 0 {
+					in[6]++
+					processBlock(&addresses, &in, &zero)
+					processBlock(&addresses, &addresses, &zero)
+				}
+				random = addresses[index%blockLength]
+			} else {
+				random = B[prev][0]
+			}
+			newOffset := indexAlpha(random, lanes, segments, threads, n, slice, lane, index)
+			// Version 1.0 overwrites the blocks of later passes instead of
+			// XORing them. The first pass starts from zeroed memory, where
+			// both are the same.
+			if version == Version10 {
+				processBlock(&B[offset], &B[prev], &B[newOffset])
+			} else {
+				processBlockXOR(&B[offset], &B[prev], &B[newOffset])
+			}
+			index, offset = index+1, offset+1
+		}
+	}
+
+	sem := make(chan struct{}, workers)
+	done := ctx.Done()
+	for n := uint32(0); n < time; n++ {
+		for slice := uint32(0); slice < syncPoints; slice++ {
+			select {
+			case <-done:
+				return ctx.Err()
+			default:
+			}
+
+			var wg sync.WaitGroup
+			for lane := uint32(0); lane < threads; lane++ {
+				sem <- struct{}{}
+				wg.Add(1)
+				lane := lane
+				pool.run(func() {
+					processSegment(n, slice, lane)
+					<-sem
+					wg.Done()
+				})
+			}
+			wg.Wait()
+		}
+	}
+
+	return nil
+}
+
+func extractKey(B []block, memory, threads, keyLen uint32) []byte {
+	lanes := memory / threads
+	for lane := uint32(0); lane < threads-1; lane++ {
+		for i, v := range B[(lane*lanes)+lanes-1] {
+			B[memory-1][i] ^= v
+		}
+	}
+
+	var block [1024]byte
+	for i, v := range B[memory-1] {
+		binary.LittleEndian.PutUint64(block[i*8:], v)
+	}
+	key := make([]byte, keyLen)
+	blake2bHash(key, block[:])
+	return key
+}
+
+func indexAlpha(rand uint64, lanes, segments, threads, n, slice, lane, index uint32) uint32 {
+	refLane := uint32(rand>>32) % threads
+	if n == 0 && slice == 0 {
+		refLane = lane
+	}
+	m, s := 3*segments, ((slice+1)%syncPoints)*segments
+	if lane == refLane {
+		m += index
+	}
+	if n == 0 {
+		m, s = slice*segments, 0
+		if slice == 0 || lane == refLane {
+			m += index
+		}
+	}
+	if index == 0 || lane == refLane {
+		m--
+	}
+	return phi(rand, uint64(m), uint64(s), refLane, lanes)
+}
+
+func phi(rand, m, s uint64, lane, lanes uint32) uint32 {
+	p := rand & 0xFFFFFFFF
+	p = (p * p) >> 32
+	p = (p * m) >> 32
+	return lane*lanes + uint32((s+m-(p+1))%uint64(lanes))
+}
@@ -0,0 +1,50 @@
+package argon2
+
+// Implementations of the compression function G.
+const (
+	// Generic is the pure Go implementation, available everywhere.
+	Generic = "generic"
+	// SSE4 is the amd64 implementation using SSE4.1, permuting two words per register.
+	SSE4 = "sse4"
+	// AVX2 is the amd64 implementation using AVX2, permuting four words per register.
+	AVX2 = "avx2"
+)
+
+// hasSSE4 and hasAVX2 report whether the CPU supports the assembly implementations.
+// They are set at startup on amd64, unless built with the purego tag.
+var (
+	hasSSE4 bool
+	hasAVX2 bool
+)
+
+// Implementations returns the implementations of the compression function
+// available on this CPU, fastest first. Generic is always the last one.
+func Implementations() []string {
+	var impls []string
+	if hasAVX2 {
+		impls = append(impls, AVX2)
+	}
+	if hasSSE4 {
+		impls = append(impls, SSE4)
+	}
+	return append(impls, Generic)
+}
+
+// UseImplementation selects the implementation of the compression function
+// used by every derivation, the fastest available one by default. It reports
+// false, leaving the selection unchanged, when the implementation is not
+// available on this CPU. It is meant for tests and benchmarks, and must not be
+// called while keys are being derived.
+func UseImplementation(name string) bool {
+	switch {
+	case name == AVX2 && hasAVX2:
+		useAVX2, useSSE4 = true, false
+	case name == SSE4 && hasSSE4:
+		useAVX2, useSSE4 = false, true
+	case name == Generic:
+		useAVX2, useSSE4 = false, false
+	default:
+		return false
+	}
+	return true
+}
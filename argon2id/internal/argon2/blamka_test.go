@@ -0,0 +1,99 @@
+package argon2
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// withImplementation runs f with the implementation selected, then restores the fastest one.
+func withImplementation(tb testing.TB, name string, f func()) {
+	tb.Helper()
+	if !UseImplementation(name) {
+		tb.Fatalf("UseImplementation(%q) = false", name)
+	}
+	defer UseImplementation(Implementations()[0])
+
+	f()
+}
+
+func randomBlock(r *rand.Rand) *block {
+	var b block
+	for i := range b {
+		b[i] = r.Uint64()
+	}
+	return &b
+}
+
+func TestImplementations(t *testing.T) {
+	impls := Implementations()
+	if impls[len(impls)-1] != Generic {
+		t.Errorf("Implementations() = %v, expected %q last", impls, Generic)
+	}
+
+	for _, name := range []string{AVX2, SSE4, Generic} {
+		available := false
+		for _, impl := range impls {
+			available = available || impl == name
+		}
+		if got := UseImplementation(name); got != available {
+			t.Errorf("UseImplementation(%q) = %v, expectation = %v", name, got, available)
+		}
+	}
+	if UseImplementation("neon") {
+		t.Errorf("UseImplementation(%q) = true, expectation = false", "neon")
+	}
+	UseImplementation(impls[0])
+}
+
+// TestProcessBlock compares every implementation of the compression function with the generic one on random blocks.
+func TestProcessBlock(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, name := range Implementations() {
+		withImplementation(t, name, func() {
+			for i := 0; i < 64; i++ {
+				in1, in2, out := randomBlock(r), randomBlock(r), randomBlock(r)
+
+				var got, want block
+				processBlock(&got, in1, in2)
+				processBlockGeneric(&want, in1, in2, false)
+				if got != want {
+					t.Fatalf("%s: processBlock() does not match the generic implementation", name)
+				}
+
+				got, want = *out, *out
+				processBlockXOR(&got, in1, in2)
+				processBlockGeneric(&want, in1, in2, true)
+				if got != want {
+					t.Fatalf("%s: processBlockXOR() does not match the generic implementation", name)
+				}
+			}
+		})
+	}
+}
+
+// TestImplementationVectors checks the test vectors with every implementation of the compression function.
+func TestImplementationVectors(t *testing.T) {
+	for _, name := range Implementations() {
+		t.Run(name, func(t *testing.T) {
+			withImplementation(t, name, func() {
+				TestArgon2(t)
+				TestVectors(t)
+			})
+		})
+	}
+}
+
+func BenchmarkProcessBlock(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	in1, in2, out := randomBlock(r), randomBlock(r), randomBlock(r)
+	for _, name := range Implementations() {
+		b.Run(name, func(b *testing.B) {
+			withImplementation(b, name, func() {
+				b.SetBytes(blockLength * 8)
+				for i := 0; i < b.N; i++ {
+					processBlockXOR(out, in1, in2)
+				}
+			})
+		})
+	}
+}
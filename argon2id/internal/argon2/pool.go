@@ -0,0 +1,50 @@
+package argon2
+
+import (
+	"runtime"
+	"sync"
+)
+
+// Pool is a fixed set of goroutines computing the lanes of Derive calls,
+// which bounds the number of lanes computed at once across all the calls
+// sharing it. Calls wait for a worker to be free before computing a lane.
+type Pool struct {
+	tasks     chan func()
+	closeOnce sync.Once
+}
+
+// NewPool starts a pool of the given number of workers, or GOMAXPROCS workers
+// when it is not positive.
+func NewPool(workers int) *Pool {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	p := &Pool{tasks: make(chan func())}
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+func (p *Pool) work() {
+	for task := range p.tasks {
+		task()
+	}
+}
+
+// run runs the task on a worker, waiting for one to be free.
+// A nil pool runs the task on a new goroutine.
+func (p *Pool) run(task func()) {
+	if p == nil {
+		go task()
+		return
+	}
+	p.tasks <- task
+}
+
+// Close stops the workers once they are done with their current lanes.
+// The pool must not be used by Derive calls after it is closed.
+func (p *Pool) Close() {
+	p.closeOnce.Do(func() { close(p.tasks) })
+}
@@ -0,0 +1,79 @@
+package argon2
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	const workers = 3
+
+	pool := NewPool(workers)
+	defer pool.Close()
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		pool.run(func() {
+			defer wg.Done()
+
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+	}
+	wg.Wait()
+
+	if peak > workers {
+		t.Errorf("%d tasks ran at once, expected at most %d", peak, workers)
+	}
+}
+
+func TestDerivePool(t *testing.T) {
+	pool := NewPool(2)
+	defer pool.Close()
+
+	want := Key(Argon2id, genKatPassword, genKatSalt, genKatSecret, genKatAAD, 3, 32, 4, 32)
+
+	// Concurrent calls with more lanes than workers share the pool.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			hash, err := Derive(context.Background(), &Input{
+				Mode:     Argon2id,
+				Password: genKatPassword,
+				Salt:     genKatSalt,
+				Secret:   genKatSecret,
+				Data:     genKatAAD,
+				Time:     3,
+				Memory:   32,
+				Lanes:    4,
+				KeyLen:   32,
+				Pool:     pool,
+			})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !bytes.Equal(hash, want) {
+				t.Errorf("derived key does not match - got: %s , want: %s", hex.EncodeToString(hash), hex.EncodeToString(want))
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,160 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bcrypt computes bcrypt hashes from a given salt and cost.
+//
+// It is derived from golang.org/x/crypto/bcrypt, which only computes hashes with random salts,
+// so that the argon2id package can recompute the bcrypt hashes it wraps.
+package bcrypt
+
+import (
+	"encoding/base64"
+	"errors"
+
+	"golang.org/x/crypto/blowfish"
+)
+
+const (
+	MinCost = 4  // the minimum cost accepted by Hash
+	MaxCost = 31 // the maximum cost accepted by Hash
+
+	// EncodedSaltSize is the length of an encoded salt.
+	EncodedSaltSize = 22
+	// EncodedHashSize is the length of an encoded hash.
+	EncodedHashSize = 31
+
+	maxSaltSize        = 16
+	maxCryptedHashSize = 23
+)
+
+var (
+	ErrInvalidCost = errors.New("bcrypt: cost out of range")
+	ErrInvalidSalt = errors.New("bcrypt: invalid salt")
+	ErrInvalidHash = errors.New("bcrypt: invalid hash")
+)
+
+const alphabet = "./ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+var bcEncoding = base64.NewEncoding(alphabet).WithPadding(base64.NoPadding)
+
+// magicCipherData is an IV for the 64 Blowfish encryption calls in
+// bcrypt(). It's the string "OrpheanBeholderScryDoubt" in big-endian bytes.
+var magicCipherData = []byte{
+	0x4f, 0x72, 0x70, 0x68,
+	0x65, 0x61, 0x6e, 0x42,
+	0x65, 0x68, 0x6f, 0x6c,
+	0x64, 0x65, 0x72, 0x53,
+	0x63, 0x72, 0x79, 0x44,
+	0x6f, 0x75, 0x62, 0x74,
+}
+
+// Hash returns the encoded bcrypt hash of the password for the given cost and encoded salt,
+// the last 31 characters of a bcrypt string.
+func Hash(password []byte, cost int, salt string) ([]byte, error) {
+	if cost < MinCost || cost > MaxCost {
+		return nil, ErrInvalidCost
+	}
+
+	csalt, err := decodeSalt(salt)
+	if err != nil {
+		return nil, err
+	}
+
+	cipherData := make([]byte, len(magicCipherData))
+	copy(cipherData, magicCipherData)
+
+	c, err := expensiveBlowfishSetup(password, uint32(cost), csalt)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < 24; i += 8 {
+		for j := 0; j < 64; j++ {
+			c.Encrypt(cipherData[i:i+8], cipherData[i:i+8])
+		}
+	}
+
+	// Bug compatibility with C bcrypt implementations. We only encode 23 of
+	// the 24 bytes encrypted.
+	hsh := make([]byte, bcEncoding.EncodedLen(maxCryptedHashSize))
+	bcEncoding.Encode(hsh, cipherData[:maxCryptedHashSize])
+	return hsh, nil
+}
+
+// CheckSalt returns ErrInvalidSalt when the encoded salt cannot be used by Hash.
+func CheckSalt(salt string) error {
+	_, err := decodeSalt(salt)
+	return err
+}
+
+// decodeSalt decodes an encoded salt. The last character of a salt only has 2 significant bits,
+// which are the only ones used, as done by the C implementations.
+func decodeSalt(salt string) ([]byte, error) {
+	if len(salt) != EncodedSaltSize {
+		return nil, ErrInvalidSalt
+	}
+
+	csalt := make([]byte, bcEncoding.DecodedLen(len(salt)))
+	n, err := bcEncoding.Decode(csalt, []byte(salt))
+	if err != nil || n != maxSaltSize {
+		return nil, ErrInvalidSalt
+	}
+
+	return csalt, nil
+}
+
+func expensiveBlowfishSetup(key []byte, cost uint32, csalt []byte) (*blowfish.Cipher, error) {
+	// Bug compatibility with C bcrypt implementations. They use the trailing
+	// NULL in the key string during expansion.
+	// We copy the key to prevent changing the underlying array.
+	ckey := append(key[:len(key):len(key)], 0)
+
+	c, err := blowfish.NewSaltedCipher(ckey, csalt)
+	if err != nil {
+		return nil, err
+	}
+
+	var i, rounds uint64
+	rounds = 1 << cost
+	for i = 0; i < rounds; i++ {
+		blowfish.ExpandKey(ckey, c)
+		blowfish.ExpandKey(csalt, c)
+	}
+
+	return c, nil
+}
+
+// Parse splits a bcrypt string, $2a$, $2b$ or $2y$ followed by the cost, the encoded salt and the encoded hash,
+// into its cost, salt and hash.
+func Parse(hash string) (cost int, salt string, sum []byte, err error) {
+	if len(hash) != 7+EncodedSaltSize+EncodedHashSize {
+		return 0, "", nil, ErrInvalidHash
+	}
+
+	switch hash[:4] {
+	case "$2a$", "$2b$", "$2y$":
+	default:
+		return 0, "", nil, ErrInvalidHash
+	}
+
+	if hash[4] < '0' || hash[4] > '9' || hash[5] < '0' || hash[5] > '9' || hash[6] != '$' {
+		return 0, "", nil, ErrInvalidHash
+	}
+	cost = int(hash[4]-'0')*10 + int(hash[5]-'0')
+	if cost < MinCost || cost > MaxCost {
+		return 0, "", nil, ErrInvalidCost
+	}
+
+	salt = hash[7 : 7+EncodedSaltSize]
+	if _, err = decodeSalt(salt); err != nil {
+		return 0, "", nil, err
+	}
+
+	sum = []byte(hash[7+EncodedSaltSize:])
+	if _, err = bcEncoding.DecodeString(string(sum)); err != nil {
+		return 0, "", nil, ErrInvalidHash
+	}
+
+	return cost, salt, sum, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2011 The Go Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bcrypt
+
+import (
+	"testing"
+
+	xbcrypt "golang.org/x/crypto/bcrypt"
+)
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		password string
+		hash     string
+	}{
+		{"", "$2a$06$DCq7YPn5Rq63x1Lad4cll.TV4S6ytwfsfvkgY8jIucDrjc8deX1s."},
+		{"a", "$2a$06$m0CrhHm10qJ3lXRY.5zDGO3rS2KdeeWLuGmsfGlMfOxih58VYVfxe"},
+		{"abc", "$2a$06$If6bvum7DFjUnE9p2uDeDu0YHzrHM6tf.iqN8.yx.jNN1ILEf7h0i"},
+		{"abcdefghijklmnopqrstuvwxyz", "$2a$06$.rCVZVOThsIa97pEDOxvGuRRgzG64bvtJ0938xuqzv18d3ZpQhstC"},
+		{"~!@#$%^&*()      ~!@#$%^&*()PNBFRD", "$2a$06$fPIsBO8qRqkjj273rfaOI.HtSV9jLDpTbZn782DC6/t7qT67P6FfO"},
+	}
+
+	for _, tt := range tests {
+		salt := tt.hash[7 : 7+EncodedSaltSize]
+		got, err := Hash([]byte(tt.password), 6, salt)
+		if err != nil {
+			t.Fatalf("Hash(%q) error = %v", tt.password, err)
+		}
+		if expected := tt.hash[7+EncodedSaltSize:]; string(got) != expected {
+			t.Errorf("Hash(%q) = %s, expectation = %s", tt.password, got, expected)
+		}
+	}
+}
+
+func TestHashMatchesXCrypto(t *testing.T) {
+	hash, err := xbcrypt.GenerateFromPassword([]byte("foo123"), xbcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Hash([]byte("foo123"), xbcrypt.MinCost, string(hash[7:7+EncodedSaltSize]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(hash[7+EncodedSaltSize:]) {
+		t.Errorf("Hash() = %s, expectation = %s", got, hash[7+EncodedSaltSize:])
+	}
+}
+
+func TestHashInvalid(t *testing.T) {
+	if _, err := Hash([]byte("foo123"), 3, "DCq7YPn5Rq63x1Lad4cll."); err != ErrInvalidCost {
+		t.Errorf("Hash() error = %v, expectation = %v", err, ErrInvalidCost)
+	}
+	if _, err := Hash([]byte("foo123"), 6, "DCq7YPn5Rq63x1Lad4cll"); err != ErrInvalidSalt {
+		t.Errorf("Hash() error = %v, expectation = %v", err, ErrInvalidSalt)
+	}
+	if _, err := Hash([]byte("foo123"), 6, "DCq7YPn5Rq63x1Lad4cll+"); err != ErrInvalidSalt {
+		t.Errorf("Hash() error = %v, expectation = %v", err, ErrInvalidSalt)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		hash        string
+		cost        int
+		salt        string
+		expectedErr error
+	}{
+		{
+			name: "$2a$",
+			hash: "$2a$06$DCq7YPn5Rq63x1Lad4cll.TV4S6ytwfsfvkgY8jIucDrjc8deX1s.",
+			cost: 6,
+			salt: "DCq7YPn5Rq63x1Lad4cll.",
+		},
+		{
+			name: "$2y$",
+			hash: "$2y$10$DCq7YPn5Rq63x1Lad4cll.TV4S6ytwfsfvkgY8jIucDrjc8deX1s.",
+			cost: 10,
+			salt: "DCq7YPn5Rq63x1Lad4cll.",
+		},
+		{
+			name:        "Unknown version",
+			hash:        "$2x$06$DCq7YPn5Rq63x1Lad4cll.TV4S6ytwfsfvkgY8jIucDrjc8deX1s.",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Truncated",
+			hash:        "$2a$06$DCq7YPn5Rq63x1Lad4cll.TV4S6ytwfsfvkgY8jIucDrjc8deX1s",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Cost out of range",
+			hash:        "$2a$32$DCq7YPn5Rq63x1Lad4cll.TV4S6ytwfsfvkgY8jIucDrjc8deX1s.",
+			expectedErr: ErrInvalidCost,
+		},
+		{
+			name:        "Invalid salt",
+			hash:        "$2a$06$DCq7YPn5Rq63x1Lad4cl+.TV4S6ytwfsfvkgY8jIucDrjc8deX1s.",
+			expectedErr: ErrInvalidSalt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cost, salt, sum, err := Parse(tt.hash)
+			if err != tt.expectedErr {
+				t.Fatalf("Parse() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if cost != tt.cost || salt != tt.salt || string(sum) != tt.hash[7+EncodedSaltSize:] {
+				t.Errorf("Parse() = %d, %s, %s", cost, salt, sum)
+			}
+		})
+	}
+}
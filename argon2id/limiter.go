@@ -0,0 +1,106 @@
+package argon2id
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// limiter is a weighted semaphore admitting calls while the sum of their memory stays within a budget.
+// Calls that cannot be admitted right away wait in a bounded FIFO queue, so that large calls are not starved by small ones.
+type limiter struct {
+	mu       sync.Mutex
+	budget   uint64
+	used     uint64
+	maxQueue int
+	waiters  list.List
+}
+
+// waiter is a call waiting in the queue of a limiter.
+type waiter struct {
+	weight uint64
+	ready  chan struct{}
+}
+
+// newLimiter returns a limiter for the given memory budget, in KiB, and maximum number of waiting calls.
+func newLimiter(budget uint64, maxQueue int) *limiter {
+	return &limiter{budget: budget, maxQueue: maxQueue}
+}
+
+// acquire admits a call using weight KiB of memory, waiting for memory to be released when needed.
+// Returns ErrOverloaded when the weight exceeds the whole budget or the queue is full, or the context error when ctx is
+// done before the call is admitted. A nil limiter admits every call.
+func (l *limiter) acquire(ctx context.Context, weight uint64) error {
+	if l == nil {
+		return nil
+	}
+
+	if weight > l.budget {
+		return ErrOverloaded
+	}
+
+	l.mu.Lock()
+	if l.used+weight <= l.budget && l.waiters.Len() == 0 {
+		l.used += weight
+		l.mu.Unlock()
+		return nil
+	}
+
+	if l.waiters.Len() >= l.maxQueue {
+		l.mu.Unlock()
+		return ErrOverloaded
+	}
+
+	w := &waiter{weight: weight, ready: make(chan struct{})}
+	elem := l.waiters.PushBack(w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		select {
+		case <-w.ready:
+			// Admitted while the context was being canceled: give the memory back.
+			l.used -= weight
+		default:
+			l.waiters.Remove(elem)
+		}
+		// Removing a waiter may unblock the ones behind it.
+		l.notify()
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// release gives back the memory of a call admitted by acquire.
+func (l *limiter) release(weight uint64) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	l.used -= weight
+	l.notify()
+	l.mu.Unlock()
+}
+
+// notify admits the waiting calls in order, as long as they fit in the budget. The caller must hold l.mu.
+func (l *limiter) notify() {
+	for {
+		front := l.waiters.Front()
+		if front == nil {
+			return
+		}
+
+		w := front.Value.(*waiter)
+		if l.used+w.weight > l.budget {
+			return
+		}
+
+		l.used += w.weight
+		l.waiters.Remove(front)
+		close(w.ready)
+	}
+}
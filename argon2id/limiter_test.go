@@ -0,0 +1,139 @@
+package argon2id
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := newLimiter(100, 1)
+
+	if err := l.acquire(ctx, 101); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("limiter.acquire() above the budget error = %v, expectation = %v", err, ErrOverloaded)
+	}
+
+	if err := l.acquire(ctx, 60); err != nil {
+		t.Fatalf("limiter.acquire() error = %v", err)
+	}
+
+	// The second call does not fit and waits in the queue, which is then full.
+	admitted := make(chan error, 1)
+	go func() { admitted <- l.acquire(ctx, 60) }()
+	waitForWaiters(t, l, 1)
+
+	if err := l.acquire(ctx, 10); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("limiter.acquire() with a full queue error = %v, expectation = %v", err, ErrOverloaded)
+	}
+
+	l.release(60)
+	if err := <-admitted; err != nil {
+		t.Errorf("limiter.acquire() of the waiting call error = %v", err)
+	}
+
+	// A canceled waiter leaves the queue without taking any memory.
+	canceled, cancel := context.WithCancel(ctx)
+	go func() { admitted <- l.acquire(canceled, 60) }()
+	waitForWaiters(t, l, 1)
+	cancel()
+	if err := <-admitted; err != context.Canceled {
+		t.Errorf("limiter.acquire() of the canceled call error = %v, expectation = %v", err, context.Canceled)
+	}
+
+	l.release(60)
+	if l.used != 0 || l.waiters.Len() != 0 {
+		t.Errorf("limiter used = %d, waiters = %d after releasing everything", l.used, l.waiters.Len())
+	}
+}
+
+func TestLimiterNeverExceedsBudget(t *testing.T) {
+	l := newLimiter(100, 100)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		used uint64
+		peak uint64
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(weight uint64) {
+			defer wg.Done()
+			if err := l.acquire(context.Background(), weight); err != nil {
+				t.Errorf("limiter.acquire() error = %v", err)
+				return
+			}
+
+			mu.Lock()
+			used += weight
+			if used > peak {
+				peak = used
+			}
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			used -= weight
+			mu.Unlock()
+			l.release(weight)
+		}(uint64(10 + i%5*10))
+	}
+	wg.Wait()
+
+	if peak > 100 {
+		t.Errorf("limiter admitted %d KiB for a budget of 100 KiB", peak)
+	}
+}
+
+func TestHasherMemoryBudget(t *testing.T) {
+	if _, err := NewHasher(WithMemoryBudget(0, 1)); !errors.Is(err, ErrInvalidMemoryBudget) {
+		t.Errorf("NewHasher() error = %v, expectation = %v", err, ErrInvalidMemoryBudget)
+	}
+
+	h, err := NewHasher(WithMemoryBudget(64, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := h.Generate([]byte("foo123"), testParams)
+	if err != nil {
+		t.Fatalf("Hasher.Generate() error = %v", err)
+	}
+	if err = h.Compare(hash, []byte("foo123")); err != nil {
+		t.Errorf("Hasher.Compare() error = %v", err)
+	}
+
+	// Both generation and verification are refused when they do not fit in the budget.
+	big := *testParams
+	big.Memory = 128
+	if _, err = h.Generate([]byte("foo123"), &big); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Hasher.Generate() error = %v, expectation = %v", err, ErrOverloaded)
+	}
+
+	const costly = "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM"
+	if err = h.Compare(costly, []byte("foo123")); !errors.Is(err, ErrOverloaded) {
+		t.Errorf("Hasher.Compare() error = %v, expectation = %v", err, ErrOverloaded)
+	}
+}
+
+// waitForWaiters waits until n calls are queued in the limiter.
+func waitForWaiters(t *testing.T, l *limiter, n int) {
+	t.Helper()
+
+	for i := 0; i < 1000; i++ {
+		l.mu.Lock()
+		queued := l.waiters.Len()
+		l.mu.Unlock()
+
+		if queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("limiter never queued %d calls", n)
+}
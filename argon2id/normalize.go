@@ -0,0 +1,52 @@
+package argon2id
+
+import (
+	"errors"
+
+	"golang.org/x/text/secure/precis"
+)
+
+// NormalizationOpaqueString is the normalization of passwords defined by the OpaqueString profile of RFC 8265:
+// non-ASCII spaces are mapped to the ASCII space, then the password is normalized to NFC, and passwords that are empty
+// or contain control or other disallowed characters are rejected.
+// It is recorded in the hash with the norm parameter.
+const NormalizationOpaqueString = "opaquestring"
+
+var ErrInvalidPassword = errors.New("the password is not allowed by the PRECIS OpaqueString profile")
+
+// WithNormalization normalizes the passwords of new hashes with NormalizationOpaqueString, so that they verify however
+// their characters were composed when typed. Hashes are normalized when verifying only if they were when generated,
+// so that hashes with and without normalization coexist, and the hashes without it need a rehash.
+// Passwords that the profile does not allow are rejected with ErrInvalidPassword.
+func WithNormalization() Option {
+	return func(h *Hasher) error {
+		h.normalization = NormalizationOpaqueString
+		return nil
+	}
+}
+
+// normalize applies the normalization to the password.
+// Returns ErrInvalidPassword when the normalization does not allow the password.
+func normalize(normalization string, pass []byte) ([]byte, error) {
+	switch normalization {
+	case "":
+		return pass, nil
+	case NormalizationOpaqueString:
+		normalized, err := precis.OpaqueString.Bytes(pass)
+		if err != nil {
+			return nil, ErrInvalidPassword
+		}
+		return normalized, nil
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+}
+
+// decodeNormalization decodes the norm parameter of a hash.
+func decodeNormalization(value string) (string, error) {
+	if value != NormalizationOpaqueString {
+		return "", &UnsupportedError{Field: FieldParams, Value: value, Err: ErrUnknownAlgorithm}
+	}
+
+	return value, nil
+}
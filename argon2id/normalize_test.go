@@ -0,0 +1,107 @@
+package argon2id
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestHasherNormalization(t *testing.T) {
+	h, err := NewHasher(WithNormalization(), WithParams(testParams))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	composed := "caf\u00e9 pass"
+	hash, err := h.Generate([]byte(composed), nil)
+	if err != nil {
+		t.Fatalf("Hasher.Generate() error = %v", err)
+	}
+	if !strings.Contains(hash, ",norm=opaquestring$") {
+		t.Errorf("Hasher.Generate() = %s, expected the norm parameter", hash)
+	}
+
+	tests := []struct {
+		name        string
+		pass        string
+		expectedErr error
+	}{
+		{
+			name:        "Composed",
+			pass:        composed,
+			expectedErr: nil,
+		},
+		{
+			name:        "Decomposed",
+			pass:        "cafe\u0301 pass",
+			expectedErr: nil,
+		},
+		{
+			name:        "Non-breaking space",
+			pass:        "caf\u00e9\u00a0pass",
+			expectedErr: nil,
+		},
+		{
+			name:        "Other password",
+			pass:        "cafe pass",
+			expectedErr: ErrPasswordNotMatch,
+		},
+		{
+			name:        "Disallowed character",
+			pass:        "caf\u00e9\x07pass",
+			expectedErr: ErrPasswordNotMatch,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Hashes record their normalization, so that any hasher verifies them.
+			if err := CompareHashAndPassword(hash, []byte(tt.pass)); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("CompareHashAndPassword() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+		})
+	}
+
+	for _, pass := range []string{"", "foo\x00123"} {
+		if _, err = h.Generate([]byte(pass), nil); !errors.Is(err, ErrInvalidPassword) {
+			t.Errorf("Hasher.Generate(%q) error = %v, expectation = %v", pass, err, ErrInvalidPassword)
+		}
+	}
+}
+
+func TestHasherNormalizationRehash(t *testing.T) {
+	h, err := NewHasher(WithNormalization(), WithParams(testParams))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := GenerateFromPassword([]byte("cafe\u0301"), testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Old hashes of the raw password still verify with the raw password only.
+	if err = h.Compare(raw, []byte("caf\u00e9")); !errors.Is(err, ErrPasswordNotMatch) {
+		t.Errorf("Hasher.Compare() error = %v, expectation = %v", err, ErrPasswordNotMatch)
+	}
+
+	newHash, upgraded, err := h.VerifyAndUpgrade(raw, []byte("cafe\u0301"), nil)
+	if err != nil || !upgraded {
+		t.Fatalf("Hasher.VerifyAndUpgrade() = %s, %v, %v, expected an upgrade", newHash, upgraded, err)
+	}
+	if err = h.Compare(newHash, []byte("caf\u00e9")); err != nil {
+		t.Errorf("Hasher.Compare() error = %v", err)
+	}
+
+	if rehash, err := h.NeedsRehash(newHash, nil); err != nil || rehash {
+		t.Errorf("Hasher.NeedsRehash() = %v, %v, expectation = false", rehash, err)
+	}
+	// Hashers without normalization leave normalized hashes alone.
+	if rehash, err := NeedsRehash(newHash, testParams); err != nil || rehash {
+		t.Errorf("NeedsRehash() = %v, %v, expectation = false", rehash, err)
+	}
+
+	if _, err = Parse(strings.Replace(newHash, "norm=opaquestring", "norm=nfkc", 1)); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("Parse() error = %v, expectation = %v", err, ErrUnknownAlgorithm)
+	}
+}
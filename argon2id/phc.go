@@ -0,0 +1,206 @@
+package argon2id
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+)
+
+// b64 is the B64 encoding of the PHC string format: standard base64 without padding,
+// rejecting non-zero trailing bits so that every value has a single encoding.
+var b64 = base64.RawStdEncoding.Strict()
+
+// phcString stores the fields of a hash in the PHC string format:
+//
+//	$<id>[$v=<version>][$<param>=<value>(,<param>=<value>)*][$<salt>[$<hash>]]
+//
+// See https://github.com/P-H-C/phc-string-format/blob/master/phc-sf-spec.md.
+type phcString struct {
+	id      string
+	version string
+	params  []phcParam
+	salt    string
+	hash    string
+}
+
+// phcParam stores a parameter of a PHC string.
+type phcParam struct {
+	name  string
+	value string
+}
+
+// parsePHC splits a PHC string into its fields, checking their syntax.
+// The fields are not decoded, as their meaning depends on the function identified by the string.
+// Syntax errors are reported as a ParseError for the failing field.
+func parsePHC(s string) (*phcString, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, &ParseError{Field: FieldAlgorithm, Err: ErrInvalidHash}
+	}
+
+	fields := strings.Split(s[1:], "$")
+	ps := &phcString{id: fields[0]}
+	if !isPHCSymbol(ps.id) {
+		return nil, &ParseError{Field: FieldAlgorithm, Err: ErrInvalidHash}
+	}
+	fields = fields[1:]
+
+	// The version is the only parameter of its own field.
+	if len(fields) > 0 && strings.HasPrefix(fields[0], "v=") && !strings.Contains(fields[0], ",") {
+		ps.version = fields[0][len("v="):]
+		if _, err := parseDecimal(ps.version, 32); err != nil {
+			return nil, parseError(FieldVersion, err)
+		}
+		fields = fields[1:]
+	}
+
+	// The salt and hash never contain '=', which tells the parameters apart.
+	if len(fields) > 0 && strings.Contains(fields[0], "=") {
+		for _, param := range strings.Split(fields[0], ",") {
+			i := strings.IndexByte(param, '=')
+			if i < 0 {
+				return nil, &ParseError{Field: FieldParams, Err: ErrInvalidHash}
+			}
+
+			name, value := param[:i], param[i+1:]
+			if !isPHCSymbol(name) || value == "" || !isPHCValue(value) {
+				return nil, &ParseError{Field: FieldParams, Err: ErrInvalidHash}
+			}
+			ps.params = append(ps.params, phcParam{name: name, value: value})
+		}
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 {
+		ps.salt = fields[0]
+		if ps.salt == "" || !isPHCValue(ps.salt) {
+			return nil, &ParseError{Field: FieldSalt, Err: ErrInvalidHash}
+		}
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 {
+		ps.hash = fields[0]
+		if ps.hash == "" || !isB64(ps.hash) {
+			return nil, &ParseError{Field: FieldKey, Err: ErrInvalidHash}
+		}
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 {
+		return nil, &ParseError{Field: FieldKey, Err: ErrInvalidHash}
+	}
+
+	return ps, nil
+}
+
+// String returns the PHC string representation of the fields.
+func (ps *phcString) String() string {
+	var b strings.Builder
+	b.WriteString("$")
+	b.WriteString(ps.id)
+
+	if ps.version != "" {
+		b.WriteString("$v=")
+		b.WriteString(ps.version)
+	}
+
+	for i, param := range ps.params {
+		if i == 0 {
+			b.WriteString("$")
+		} else {
+			b.WriteString(",")
+		}
+		b.WriteString(param.name)
+		b.WriteString("=")
+		b.WriteString(param.value)
+	}
+
+	if ps.salt != "" {
+		b.WriteString("$")
+		b.WriteString(ps.salt)
+
+		if ps.hash != "" {
+			b.WriteString("$")
+			b.WriteString(ps.hash)
+		}
+	}
+
+	return b.String()
+}
+
+// isPHCSymbol reports whether s is a valid function identifier or parameter name: [a-z0-9-]{1,32}.
+func isPHCSymbol(s string) bool {
+	if len(s) == 0 || len(s) > 32 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !('a' <= c && c <= 'z') && !('0' <= c && c <= '9') && c != '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isPHCValue reports whether s only contains the characters allowed in parameter values and salts: [a-zA-Z0-9/+.-].
+func isPHCValue(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !isB64Char(c) && c != '.' && c != '-' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isB64 reports whether s only contains B64 characters: [A-Za-z0-9+/].
+func isB64(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isB64Char(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isB64Char(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') || c == '+' || c == '/'
+}
+
+// parseDecimal parses a non-negative PHC decimal value, which must not have a sign or leading zeros.
+func parseDecimal(s string, bitSize int) (uint64, error) {
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, ErrInvalidHash
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return 0, ErrInvalidHash
+		}
+	}
+
+	n, err := strconv.ParseUint(s, 10, bitSize)
+	if err != nil {
+		return 0, ErrInvalidHash
+	}
+
+	return n, nil
+}
+
+// decodeB64 decodes a canonical B64 value.
+func decodeB64(s string) ([]byte, error) {
+	if !isB64(s) {
+		return nil, ErrInvalidHash
+	}
+
+	decoded, err := b64.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidHash
+	}
+
+	return decoded, nil
+}
@@ -0,0 +1,160 @@
+package argon2id
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParsePHC(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr bool
+	}{
+		// Examples of the PHC string format specification.
+		{name: "Spec example 1", hash: "$argon2i$m=120,t=5000,p=2"},
+		{name: "Spec example 2", hash: "$argon2i$m=120,t=4294967295,p=2"},
+		{name: "Spec example 3", hash: "$argon2i$m=2040,t=5000,p=255"},
+		{name: "Spec example 4", hash: "$argon2i$m=120,t=5000,p=2,keyid=Hj5+dsK0"},
+		{name: "Spec example 5", hash: "$argon2i$m=120,t=5000,p=2,keyid=Hj5+dsK0ZQ"},
+		{name: "Spec example 6", hash: "$argon2i$m=120,t=5000,p=2,keyid=Hj5+dsK0ZQA"},
+		{name: "Spec example 7", hash: "$argon2i$m=120,t=5000,p=2,data=sRlHhRmKUGzdOmXn01XmXygd5Kc"},
+		{name: "Spec example 8", hash: "$argon2i$m=120,t=5000,p=2,keyid=Hj5+dsK0,data=sRlHhRmKUGzdOmXn01XmXygd5Kc"},
+		{name: "Spec example 9", hash: "$argon2i$m=120,t=5000,p=2$/LtFjH5rVL8"},
+		{name: "Spec example 10", hash: "$argon2i$m=120,t=5000,p=2$4fXXG0spB92WPB1NitT8/OH0VKI"},
+		{name: "Spec example 11", hash: "$argon2i$m=120,t=5000,p=2$BwUgJHHQaynE+a4nZrYRzOllGSjjxuxNXxyNRUtI6Dlw/zlbt6PzOL8Onfqs6TcG"},
+		{name: "Spec example 12", hash: "$argon2i$m=120,t=5000,p=2,keyid=Hj5+dsK0$4fXXG0spB92WPB1NitT8/OH0VKI"},
+		{name: "Spec example 13", hash: "$argon2i$m=120,t=5000,p=2,data=sRlHhRmKUGzdOmXn01XmXygd5Kc$4fXXG0spB92WPB1NitT8/OH0VKI"},
+		{name: "Spec example 14", hash: "$argon2i$m=120,t=5000,p=2,keyid=Hj5+dsK0,data=sRlHhRmKUGzdOmXn01XmXygd5Kc$4fXXG0spB92WPB1NitT8/OH0VKI"},
+		{name: "Spec example 15", hash: "$argon2i$m=120,t=5000,p=2$4fXXG0spB92WPB1NitT8/OH0VKI$iPxVq0hj9ywBdaHw6IW1OgPnDQp3hIzsN0H4t3XFeIM"},
+		{name: "Spec example 16", hash: "$argon2i$m=120,t=5000,p=2,keyid=Hj5+dsK0,data=sRlHhRmKUGzdOmXn01XmXygd5Kc$4fXXG0spB92WPB1NitT8/OH0VKI$iPxVq0hj9ywBdaHw6IW1OgPnDQp3hIzsN0H4t3XFeIM"},
+		{name: "Spec example 17", hash: "$argon2i$v=19$m=120,t=5000,p=2,keyid=Hj5+dsK0,data=sRlHhRmKUGzdOmXn01XmXygd5Kc$4fXXG0spB92WPB1NitT8/OH0VKI$iPxVq0hj9ywBdaHw6IW1OgPnDQp3hIzsN0H4t3XFeIM"},
+		{name: "Identifier only", hash: "$argon2id"},
+		{name: "Version only", hash: "$argon2id$v=19"},
+		{name: "Salt without parameters", hash: "$argon2id$v=19$4fXXG0spB92WPB1NitT8/OH0VKI"},
+		{name: "Missing leading separator", hash: "argon2id$v=19$m=120,t=5000,p=2", wantErr: true},
+		{name: "Empty identifier", hash: "$$v=19", wantErr: true},
+		{name: "Uppercase identifier", hash: "$Argon2id$v=19", wantErr: true},
+		{name: "Identifier too long", hash: "$argon2id-argon2id-argon2id-argon2id", wantErr: true},
+		{name: "Version with leading zero", hash: "$argon2id$v=019", wantErr: true},
+		{name: "Version with sign", hash: "$argon2id$v=+19", wantErr: true},
+		{name: "Empty version", hash: "$argon2id$v=", wantErr: true},
+		{name: "Parameter without value", hash: "$argon2id$m=120,t,p=2", wantErr: true},
+		{name: "Empty parameter value", hash: "$argon2id$m=120,t=,p=2", wantErr: true},
+		{name: "Invalid parameter name", hash: "$argon2id$M=120", wantErr: true},
+		{name: "Invalid parameter value", hash: "$argon2id$m=12 0", wantErr: true},
+		{name: "Empty salt", hash: "$argon2id$m=120$$iPxVq0hj9ywBdaHw6IW1OgPnDQp3hIzsN0H4t3XFeIM", wantErr: true},
+		{name: "Padded hash", hash: "$argon2id$m=120$4fXXG0spB92WPB1NitT8/OH0VKI$iPxVq0hj9ywBdaHw6IW1OgPnDQp3hIzsN0H4t3XFeIM=", wantErr: true},
+		{name: "Trailing field", hash: "$argon2id$m=120$4fXXG0spB92WPB1NitT8/OH0VKI$iPxVq0hj9ywBdaHw6IW1OgPnDQp3hIzsN0H4t3XFeIM$", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ps, err := parsePHC(tt.hash)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePHC() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidHash) {
+					t.Errorf("parsePHC() error = %v, expectation = %v", err, ErrInvalidHash)
+				}
+				return
+			}
+
+			if got := ps.String(); got != tt.hash {
+				t.Errorf("phcString.String() = %s, expectation = %s", got, tt.hash)
+			}
+		})
+	}
+}
+
+func TestParseCanonical(t *testing.T) {
+	const canonical = "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM"
+
+	tests := []struct {
+		name        string
+		hash        string
+		want        string
+		expectedErr error
+	}{
+		{
+			name: "Canonical form",
+			hash: canonical,
+			want: canonical,
+		},
+		{
+			name: "Parameters in another order",
+			hash: "$argon2id$v=19$p=1,t=3,m=4096$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			want: canonical,
+		},
+		{
+			name: "Optional parameters in another order",
+			hash: "$argon2id$v=19$m=4096,t=3,p=1,data=dGVuYW50LTE,keyid=azE$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			want: "$argon2id$v=19$m=4096,t=3,p=1,keyid=azE,data=dGVuYW50LTE$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+		},
+		{
+			name: "No version field",
+			hash: "$argon2i$m=65536,t=2,p=1$c29tZXNhbHQ$9sTbSlTio3Biev89thdrlKKiCaYsjjYVJxGAL3swxpQ",
+			want: "$argon2i$v=16$m=65536,t=2,p=1$c29tZXNhbHQ$9sTbSlTio3Biev89thdrlKKiCaYsjjYVJxGAL3swxpQ",
+		},
+		{
+			name:        "Unsupported version",
+			hash:        "$argon2id$v=18$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			expectedErr: ErrIncompatibleVersion,
+		},
+		{
+			name:        "Trailing garbage after a cost",
+			hash:        "$argon2id$v=19$m=4096,t=3,p=1x$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Cost with leading zero",
+			hash:        "$argon2id$v=19$m=04096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Cost out of range",
+			hash:        "$argon2id$v=19$m=4096,t=3,p=256$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Duplicate parameter",
+			hash:        "$argon2id$v=19$m=4096,t=3,p=1,m=8192$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Unknown parameter",
+			hash:        "$argon2id$v=19$m=4096,t=3,p=1,x=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Missing cost",
+			hash:        "$argon2id$v=19$m=4096,t=3$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Salt outside of B64",
+			hash:        "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7E.zPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Missing hash",
+			hash:        "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw",
+			expectedErr: ErrInvalidHash,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := Parse(tt.hash)
+			if !errors.Is(err, tt.expectedErr) {
+				t.Fatalf("Parse() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+
+			if err == nil && h.String() != tt.want {
+				t.Errorf("Hash.String() = %s, expectation = %s", h.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package argon2id
+
+// Preset stores a named set of argon2id parameters along with where they come from.
+type Preset struct {
+	Name      string
+	Rationale string
+	Params    Params
+}
+
+var (
+	// PresetRFC9106First is the first recommended option of RFC 9106, section 4.
+	PresetRFC9106First = Preset{
+		Name:      "rfc9106-first",
+		Rationale: "RFC 9106 section 4, first recommended option: 2 GiB of memory, 1 pass, 4 lanes, 128-bit salt and 256-bit tag.",
+		Params: Params{
+			Memory:      2 * 1024 * 1024,
+			Iterations:  1,
+			Parallelism: 4,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+	}
+
+	// PresetRFC9106Second is the second recommended option of RFC 9106, section 4, for memory constrained environments.
+	PresetRFC9106Second = Preset{
+		Name:      "rfc9106-second",
+		Rationale: "RFC 9106 section 4, second recommended option when 2 GiB is not available: 64 MiB of memory, 3 passes, 4 lanes, 128-bit salt and 256-bit tag.",
+		Params: Params{
+			Memory:      64 * 1024,
+			Iterations:  3,
+			Parallelism: 4,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+	}
+
+	// PresetOWASP is the minimum argon2id configuration of the OWASP Password Storage Cheat Sheet.
+	PresetOWASP = Preset{
+		Name:      "owasp",
+		Rationale: "OWASP Password Storage Cheat Sheet minimum for argon2id: 19 MiB of memory, 2 iterations, 1 degree of parallelism, with the RFC 9106 128-bit salt and 256-bit tag.",
+		Params: Params{
+			Memory:      19 * 1024,
+			Iterations:  2,
+			Parallelism: 1,
+			SaltLength:  16,
+			KeyLength:   32,
+		},
+	}
+)
+
+// Presets lists every preset provided by the package.
+var Presets = []Preset{
+	PresetRFC9106First,
+	PresetRFC9106Second,
+	PresetOWASP,
+}
+
+// DefaultPreset returns the preset used by GenerateFromPassword when no parameters are given.
+func DefaultPreset() Preset {
+	return PresetOWASP
+}
+
+// LookupPreset returns the preset with the given name.
+func LookupPreset(name string) (Preset, bool) {
+	for _, preset := range Presets {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+
+	return Preset{}, false
+}
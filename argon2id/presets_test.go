@@ -0,0 +1,30 @@
+package argon2id
+
+import "testing"
+
+func TestPresets(t *testing.T) {
+	for _, preset := range Presets {
+		t.Run(preset.Name, func(t *testing.T) {
+			if preset.Rationale == "" {
+				t.Errorf("Preset %s has no rationale", preset.Name)
+			}
+
+			if err := preset.Params.Validate(); err != nil {
+				t.Errorf("Preset %s has invalid parameters: %v", preset.Name, err)
+			}
+
+			got, ok := LookupPreset(preset.Name)
+			if !ok || got != preset {
+				t.Errorf("LookupPreset(%q) = %+v, %v", preset.Name, got, ok)
+			}
+		})
+	}
+
+	if _, ok := LookupPreset("unknown"); ok {
+		t.Errorf("LookupPreset() found an unknown preset")
+	}
+
+	if *defaultParams() != DefaultPreset().Params {
+		t.Errorf("defaultParams() = %+v, expectation = %+v", *defaultParams(), DefaultPreset().Params)
+	}
+}
@@ -0,0 +1,355 @@
+package argon2id
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"errors"
+	"hash"
+	"math"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+var ErrUnknownAlgorithm = errors.New("no verifier is registered for the hash algorithm")
+
+// Verifier verifies passwords against hashes of a legacy algorithm.
+type Verifier interface {
+	// Compare returns nil when the password matches the hash, ErrPasswordNotMatch when it does not,
+	// and ErrInvalidHash when the hash is malformed.
+	Compare(hash string, pass []byte) error
+}
+
+// VerifierFunc adapts a function to the Verifier interface.
+type VerifierFunc func(hash string, pass []byte) error
+
+// Compare calls f(hash, pass).
+func (f VerifierFunc) Compare(hash string, pass []byte) error {
+	return f(hash, pass)
+}
+
+// limitedVerifier is a built-in verifier, which a Registry runs within the limits of its hasher.
+type limitedVerifier func(hash string, pass []byte, l *Limits) error
+
+// Compare verifies the hash without limits.
+func (f limitedVerifier) Compare(hash string, pass []byte) error {
+	return f(hash, pass, nil)
+}
+
+var (
+	// BcryptVerifier verifies bcrypt hashes in the modular crypt format: $2a$, $2b$ or $2y$, then the cost,
+	// the salt and the hash.
+	// Within a Registry, the cost of the hash counts against MaxBcryptCost, or DefaultMaxBcryptCost when it is zero.
+	BcryptVerifier Verifier = limitedVerifier(compareBcrypt)
+	// ScryptVerifier verifies scrypt hashes in the PHC string format: $scrypt$ln=<log2(N)>,r=<r>,p=<p>$<salt>$<hash>.
+	// Within a Registry, the memory of the hash, 128*r*N bytes, counts against MaxMemory and p against MaxParallelism.
+	ScryptVerifier Verifier = limitedVerifier(compareScrypt)
+	// PBKDF2Verifier verifies PBKDF2 hashes in the PHC string format: $pbkdf2-<sha1|sha256|sha512>$i=<iterations>$<salt>$<hash>.
+	// Within a Registry, the iterations of the hash count against MaxIterations.
+	PBKDF2Verifier Verifier = limitedVerifier(comparePBKDF2)
+)
+
+// Registry verifies argon2 hashes with a Hasher and legacy hashes with the Verifier registered for their prefix,
+// so that users of other systems can be migrated to argon2id as they log in.
+// New hashes are always generated by the Hasher, and legacy hashes always need a rehash.
+// The limits of the Hasher apply to the legacy hashes of BcryptVerifier, ScryptVerifier and PBKDF2Verifier as well.
+// Verifiers must be registered before the registry is used concurrently.
+type Registry struct {
+	hasher    *Hasher
+	verifiers map[string]Verifier
+}
+
+var _ PasswordHasher = (*Registry)(nil)
+
+// NewRegistry returns a Registry using the hasher for argon2 hashes, or the default configuration when h is nil.
+// BcryptVerifier, ScryptVerifier and PBKDF2Verifier are registered for their prefixes.
+func NewRegistry(h *Hasher) *Registry {
+	if h == nil {
+		h = defaultHasher
+	}
+
+	r := &Registry{hasher: h, verifiers: make(map[string]Verifier)}
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		r.Register(prefix, BcryptVerifier)
+	}
+	r.Register("$scrypt$", ScryptVerifier)
+	for _, prefix := range []string{"$pbkdf2-sha1$", "$pbkdf2-sha256$", "$pbkdf2-sha512$"} {
+		r.Register(prefix, PBKDF2Verifier)
+	}
+
+	return r
+}
+
+// Register sets the verifier of the hashes starting with prefix, replacing the one registered before, if any.
+// The longest registered prefix matching a hash wins. Hashes starting with "$argon2" are always verified by the hasher.
+func (r *Registry) Register(prefix string, v Verifier) {
+	r.verifiers[prefix] = v
+}
+
+// verifier returns the verifier of a legacy hash, or nil for argon2 hashes.
+// Returns ErrUnknownAlgorithm when no verifier matches.
+func (r *Registry) verifier(hash string) (Verifier, error) {
+	if strings.HasPrefix(hash, "$argon2") {
+		return nil, nil
+	}
+
+	var found Verifier
+	longest := -1
+	for prefix, v := range r.verifiers {
+		if len(prefix) > longest && strings.HasPrefix(hash, prefix) {
+			found, longest = v, len(prefix)
+		}
+	}
+
+	if found == nil {
+		return nil, ErrUnknownAlgorithm
+	}
+
+	return found, nil
+}
+
+// Generate generates an argon2 hash with the hasher, see Hasher.Generate.
+func (r *Registry) Generate(pass []byte, p *Params) (string, error) {
+	return r.hasher.Generate(pass, p)
+}
+
+// Compare compares the hash, argon2 or legacy, with its possible plaintext equivalent.
+// Returns ErrUnknownAlgorithm when the hash is neither an argon2 hash nor a registered legacy one.
+func (r *Registry) Compare(hash string, pass []byte) error {
+	v, err := r.verifier(hash)
+	if err != nil {
+		return err
+	}
+
+	if v == nil {
+		return r.hasher.Compare(hash, pass)
+	}
+
+	if lv, ok := v.(limitedVerifier); ok {
+		return lv(hash, pass, r.hasher.limits)
+	}
+
+	return v.Compare(hash, pass)
+}
+
+// NeedsRehash reports whether the hash must be replaced: always for legacy hashes, and as Hasher.NeedsRehash does for
+// argon2 hashes.
+// Returns ErrUnknownAlgorithm when the hash is neither an argon2 hash nor a registered legacy one.
+func (r *Registry) NeedsRehash(hash string, p *Params) (bool, error) {
+	v, err := r.verifier(hash)
+	if err != nil {
+		return false, err
+	}
+
+	if v == nil {
+		return r.hasher.NeedsRehash(hash, p)
+	}
+
+	return true, nil
+}
+
+// VerifyAndUpgrade works like Hasher.VerifyAndUpgrade, but also accepts legacy hashes, which are always upgraded to
+// an argon2 hash generated with the target parameters.
+func (r *Registry) VerifyAndUpgrade(hash string, pass []byte, target *Params) (newHash string, upgraded bool, err error) {
+	if err = r.Compare(hash, pass); err != nil {
+		return "", false, err
+	}
+
+	rehash, err := r.NeedsRehash(hash, target)
+	if err != nil {
+		return "", false, err
+	}
+
+	if !rehash {
+		return hash, false, nil
+	}
+
+	newHash, err = r.hasher.Generate(pass, target)
+	if err != nil {
+		return "", false, err
+	}
+
+	return newHash, true, nil
+}
+
+// compareBcrypt verifies a bcrypt hash within the limits.
+func compareBcrypt(hash string, pass []byte, l *Limits) error {
+	if l != nil {
+		cost, err := bcrypt.Cost([]byte(hash))
+		if err != nil {
+			return ErrInvalidHash
+		}
+		if cost > l.maxBcryptCost() {
+			return &LimitError{Param: "cost", Value: uint64(cost), Max: uint64(l.maxBcryptCost())}
+		}
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), pass)
+	switch err {
+	case nil:
+		return nil
+	case bcrypt.ErrMismatchedHashAndPassword:
+		return ErrPasswordNotMatch
+	default:
+		return ErrInvalidHash
+	}
+}
+
+// compareScrypt verifies a scrypt hash within the limits.
+func compareScrypt(hash string, pass []byte, l *Limits) error {
+	ps, err := parsePHC(hash)
+	if err != nil {
+		return err
+	}
+	if ps.id != "scrypt" || ps.version != "" {
+		return ErrInvalidHash
+	}
+
+	values, err := parseLegacyParams(ps.params, "ln", "r", "p")
+	if err != nil {
+		return err
+	}
+	if values["ln"] < 1 || values["ln"] > 62 {
+		return ErrInvalidHash
+	}
+	if err = l.checkScrypt(values["ln"], values["r"], values["p"]); err != nil {
+		return err
+	}
+
+	salt, key, err := decodeSaltAndKey(ps)
+	if err != nil {
+		return err
+	}
+
+	computed, err := scrypt.Key(pass, salt, 1<<values["ln"], int(values["r"]), int(values["p"]), len(key))
+	if err != nil {
+		return ErrInvalidHash
+	}
+
+	return compareKeys(computed, key)
+}
+
+// checkScrypt returns a LimitError when the memory of a scrypt hash, 128*r*N bytes with N = 2^ln, is above MaxMemory
+// or p is above MaxParallelism.
+func (l *Limits) checkScrypt(ln, r, p uint64) error {
+	if l == nil {
+		return nil
+	}
+
+	if l.MaxMemory > 0 {
+		// The memory in KiB is r*N/8, which does not fit in 64 bits for the largest r and ln.
+		memory := uint64(math.MaxUint64)
+		if ln+3 < 64 && r <= math.MaxUint64>>(ln+3) {
+			memory = r << ln >> 3
+		}
+		if memory > uint64(l.MaxMemory) {
+			return &LimitError{Param: "m", Value: memory, Max: uint64(l.MaxMemory)}
+		}
+	}
+	if l.MaxParallelism > 0 && p > uint64(l.MaxParallelism) {
+		return &LimitError{Param: "p", Value: p, Max: uint64(l.MaxParallelism)}
+	}
+
+	return nil
+}
+
+// comparePBKDF2 verifies a PBKDF2 hash within the limits.
+func comparePBKDF2(encoded string, pass []byte, l *Limits) error {
+	ps, err := parsePHC(encoded)
+	if err != nil {
+		return err
+	}
+	if ps.version != "" {
+		return ErrInvalidHash
+	}
+
+	var h func() hash.Hash
+	switch ps.id {
+	case "pbkdf2-sha1":
+		h = sha1.New
+	case "pbkdf2-sha256":
+		h = sha256.New
+	case "pbkdf2-sha512":
+		h = sha512.New
+	default:
+		return ErrInvalidHash
+	}
+
+	values, err := parseLegacyParams(ps.params, "i")
+	if err != nil {
+		return err
+	}
+	if values["i"] < 1 {
+		return ErrInvalidHash
+	}
+	if l != nil && l.MaxIterations > 0 && values["i"] > uint64(l.MaxIterations) {
+		return &LimitError{Param: "i", Value: values["i"], Max: uint64(l.MaxIterations)}
+	}
+
+	salt, key, err := decodeSaltAndKey(ps)
+	if err != nil {
+		return err
+	}
+
+	return compareKeys(pbkdf2.Key(pass, salt, int(values["i"]), len(key), h), key)
+}
+
+// parseLegacyParams decodes the decimal parameters of a legacy PHC string, which must be exactly the given ones, in any order.
+func parseLegacyParams(params []phcParam, names ...string) (map[string]uint64, error) {
+	if len(params) != len(names) {
+		return nil, ErrInvalidHash
+	}
+
+	values := make(map[string]uint64, len(params))
+	for _, param := range params {
+		known := false
+		for _, name := range names {
+			known = known || param.name == name
+		}
+		if _, dup := values[param.name]; !known || dup {
+			return nil, ErrInvalidHash
+		}
+
+		v, err := parseDecimal(param.value, 31)
+		if err != nil {
+			return nil, err
+		}
+		values[param.name] = v
+	}
+
+	return values, nil
+}
+
+// decodeSaltAndKey decodes the salt and hash of a legacy PHC string, which are both required. The hash must be at least
+// 4 bytes long, as for argon2 hashes.
+func decodeSaltAndKey(ps *phcString) (salt, key []byte, err error) {
+	if ps.salt == "" || ps.hash == "" {
+		return nil, nil, ErrInvalidHash
+	}
+
+	if salt, err = decodeB64(ps.salt); err != nil {
+		return nil, nil, err
+	}
+	if key, err = decodeB64(ps.hash); err != nil {
+		return nil, nil, err
+	}
+	// An empty key would match any password.
+	if len(key) < 4 {
+		return nil, nil, parseError(FieldKey, ErrKeyTooShort)
+	}
+
+	return salt, key, nil
+}
+
+// compareKeys compares a computed key with the stored one in constant time.
+func compareKeys(computed, key []byte) error {
+	if subtle.ConstantTimeCompare(computed, key) == 0 {
+		return ErrPasswordNotMatch
+	}
+
+	return nil
+}
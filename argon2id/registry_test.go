@@ -0,0 +1,351 @@
+package argon2id
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const (
+	testBcrypt       = "$2a$04$ZuaOqr6cmvAro0vFQruqCe43YWBA2egQ57/zfGUjyktJDDDzMhfr2"
+	testScrypt       = "$scrypt$ln=10,r=8,p=1$c29tZXNhbHRzb21lc2FsdA$vIL8ZcgeZ+ARI6ML3Xc6GBYZAzl9UmLRgIwpaChokKA"
+	testPBKDF2SHA1   = "$pbkdf2-sha1$i=1000$c29tZXNhbHRzb21lc2FsdA$s0wKcFnRRRVltCL6IwHlqEYRYH4"
+	testPBKDF2SHA256 = "$pbkdf2-sha256$i=1000$c29tZXNhbHRzb21lc2FsdA$9AR8oMSSG5CNjozlgk8n0y4fq2GiK54qhzcKNqhX/fw"
+	testPBKDF2SHA512 = "$pbkdf2-sha512$i=1000$c29tZXNhbHRzb21lc2FsdA$/pMeHL0gEOr78w2Z8dwCcCVv1/6LRPUKrSrf7Gz1GAyFHd98AHfwM9zGn2MmEn3d/vIi8UD3l/dsUg+zv7P9hg"
+)
+
+func TestRegistryCompare(t *testing.T) {
+	r := NewRegistry(nil)
+
+	argon2Hash, err := GenerateFromPassword([]byte("foo123"), testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		hash        string
+		pass        string
+		wantErr     bool
+		expectedErr error
+		wantRehash  bool
+	}{
+		{
+			name:       "Argon2id",
+			hash:       argon2Hash,
+			pass:       "foo123",
+			wantRehash: false,
+		},
+		{
+			name:       "Bcrypt $2a$",
+			hash:       testBcrypt,
+			pass:       "foo123",
+			wantRehash: true,
+		},
+		{
+			name:       "Bcrypt $2b$",
+			hash:       strings.Replace(testBcrypt, "$2a$", "$2b$", 1),
+			pass:       "foo123",
+			wantRehash: true,
+		},
+		{
+			name:       "Bcrypt $2y$",
+			hash:       strings.Replace(testBcrypt, "$2a$", "$2y$", 1),
+			pass:       "foo123",
+			wantRehash: true,
+		},
+		{
+			name:        "Bcrypt wrong password",
+			hash:        testBcrypt,
+			pass:        "foo124",
+			wantErr:     true,
+			expectedErr: ErrPasswordNotMatch,
+			wantRehash:  true,
+		},
+		{
+			name:        "Bcrypt truncated",
+			hash:        testBcrypt[:40],
+			pass:        "foo123",
+			wantErr:     true,
+			expectedErr: ErrInvalidHash,
+			wantRehash:  true,
+		},
+		{
+			name:       "Scrypt",
+			hash:       testScrypt,
+			pass:       "foo123",
+			wantRehash: true,
+		},
+		{
+			name:       "Scrypt params in another order",
+			hash:       strings.Replace(testScrypt, "ln=10,r=8,p=1", "p=1,r=8,ln=10", 1),
+			pass:       "foo123",
+			wantRehash: true,
+		},
+		{
+			name:        "Scrypt wrong password",
+			hash:        testScrypt,
+			pass:        "foo124",
+			wantErr:     true,
+			expectedErr: ErrPasswordNotMatch,
+			wantRehash:  true,
+		},
+		{
+			name:        "Scrypt missing parameter",
+			hash:        strings.Replace(testScrypt, "ln=10,r=8,p=1", "ln=10,r=8", 1),
+			pass:        "foo123",
+			wantErr:     true,
+			expectedErr: ErrInvalidHash,
+			wantRehash:  true,
+		},
+		{
+			name:        "Scrypt N not above 1",
+			hash:        strings.Replace(testScrypt, "ln=10", "ln=0", 1),
+			pass:        "foo123",
+			wantErr:     true,
+			expectedErr: ErrInvalidHash,
+			wantRehash:  true,
+		},
+		{
+			name:        "Scrypt key too short",
+			hash:        "$scrypt$ln=10,r=8,p=1$c29tZXNhbHRzb21lc2FsdA$AAAA",
+			pass:        "foo123",
+			wantErr:     true,
+			expectedErr: ErrKeyTooShort,
+			wantRehash:  true,
+		},
+		{
+			name:       "PBKDF2-SHA1",
+			hash:       testPBKDF2SHA1,
+			pass:       "foo123",
+			wantRehash: true,
+		},
+		{
+			name:       "PBKDF2-SHA256",
+			hash:       testPBKDF2SHA256,
+			pass:       "foo123",
+			wantRehash: true,
+		},
+		{
+			name:       "PBKDF2-SHA512",
+			hash:       testPBKDF2SHA512,
+			pass:       "foo123",
+			wantRehash: true,
+		},
+		{
+			name:        "PBKDF2 wrong password",
+			hash:        testPBKDF2SHA256,
+			pass:        "foo124",
+			wantErr:     true,
+			expectedErr: ErrPasswordNotMatch,
+			wantRehash:  true,
+		},
+		{
+			name:        "PBKDF2 without salt",
+			hash:        "$pbkdf2-sha256$i=1000",
+			pass:        "foo123",
+			wantErr:     true,
+			expectedErr: ErrInvalidHash,
+			wantRehash:  true,
+		},
+		{
+			name:        "PBKDF2 key too short",
+			hash:        "$pbkdf2-sha256$i=1000$c29tZXNhbHRzb21lc2FsdA$AAAA",
+			pass:        "foo123",
+			wantErr:     true,
+			expectedErr: ErrKeyTooShort,
+			wantRehash:  true,
+		},
+		{
+			name:        "Unknown algorithm",
+			hash:        "$1$saltsalt$hash",
+			pass:        "foo123",
+			wantErr:     true,
+			expectedErr: ErrUnknownAlgorithm,
+		},
+		{
+			name:        "Unknown argon2 variant",
+			hash:        "$argon2x$v=19$m=64,t=1,p=1$c29tZXNhbHQ$c29tZWhhc2g",
+			pass:        "foo123",
+			wantErr:     true,
+			expectedErr: ErrIncompatibleVersion,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Compare(tt.hash, []byte(tt.pass))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Registry.Compare() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Registry.Compare() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+
+			rehash, err := r.NeedsRehash(tt.hash, testParams)
+			if err == nil && rehash != tt.wantRehash {
+				t.Errorf("Registry.NeedsRehash() = %v, expectation = %v", rehash, tt.wantRehash)
+			}
+		})
+	}
+}
+
+func TestRegistryLimits(t *testing.T) {
+	h, err := NewHasher(WithLimits(&Limits{MaxMemory: 1024, MaxIterations: 1000, MaxParallelism: 1, MaxBcryptCost: 4}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewRegistry(h)
+
+	tests := []struct {
+		name        string
+		hash        string
+		wantErr     bool
+		expectedErr error
+		wantParam   string
+	}{
+		{
+			name: "Bcrypt within the limits",
+			hash: testBcrypt,
+		},
+		{
+			name:        "Bcrypt oversized cost",
+			hash:        strings.Replace(testBcrypt, "$2a$04$", "$2a$31$", 1),
+			wantErr:     true,
+			expectedErr: ErrLimitExceeded,
+			wantParam:   "cost",
+		},
+		{
+			name: "Scrypt within the limits",
+			hash: testScrypt,
+		},
+		{
+			name:        "Scrypt oversized ln",
+			hash:        strings.Replace(testScrypt, "ln=10", "ln=40", 1),
+			wantErr:     true,
+			expectedErr: ErrLimitExceeded,
+			wantParam:   "m",
+		},
+		{
+			name:        "Scrypt largest ln and r",
+			hash:        strings.Replace(testScrypt, "ln=10,r=8", "ln=62,r=2147483647", 1),
+			wantErr:     true,
+			expectedErr: ErrLimitExceeded,
+			wantParam:   "m",
+		},
+		{
+			name:        "Scrypt oversized r",
+			hash:        strings.Replace(testScrypt, "r=8", "r=1048576", 1),
+			wantErr:     true,
+			expectedErr: ErrLimitExceeded,
+			wantParam:   "m",
+		},
+		{
+			name:        "Scrypt oversized p",
+			hash:        strings.Replace(testScrypt, "p=1", "p=1024", 1),
+			wantErr:     true,
+			expectedErr: ErrLimitExceeded,
+			wantParam:   "p",
+		},
+		{
+			name: "PBKDF2 within the limits",
+			hash: testPBKDF2SHA256,
+		},
+		{
+			name:        "PBKDF2 oversized i",
+			hash:        strings.Replace(testPBKDF2SHA256, "i=1000", "i=2147483647", 1),
+			wantErr:     true,
+			expectedErr: ErrLimitExceeded,
+			wantParam:   "i",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.Compare(tt.hash, []byte("foo123"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Registry.Compare() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Registry.Compare() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+
+			var limitErr *LimitError
+			if tt.wantParam != "" && (!errors.As(err, &limitErr) || limitErr.Param != tt.wantParam) {
+				t.Errorf("Registry.Compare() error = %v, expected a LimitError for %s", err, tt.wantParam)
+			}
+		})
+	}
+
+	// The verifiers themselves are not limited.
+	if err := ScryptVerifier.Compare(testScrypt, []byte("foo123")); err != nil {
+		t.Errorf("ScryptVerifier.Compare() error = %v", err)
+	}
+
+	// Limits without MaxBcryptCost cap the cost at DefaultMaxBcryptCost.
+	h, err = NewHasher(WithLimits(&Limits{MaxMemory: 1024}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = NewRegistry(h)
+	var limitErr *LimitError
+	if err = r.Compare(strings.Replace(testBcrypt, "$2a$04$", "$2a$15$", 1), []byte("foo123")); !errors.As(err, &limitErr) || limitErr.Max != DefaultMaxBcryptCost {
+		t.Errorf("Registry.Compare() error = %v, expected the cost above %d", err, DefaultMaxBcryptCost)
+	}
+	if err = r.Compare(testBcrypt, []byte("foo123")); err != nil {
+		t.Errorf("Registry.Compare() error = %v", err)
+	}
+}
+
+func TestRegistryVerifyAndUpgrade(t *testing.T) {
+	r := NewRegistry(nil)
+
+	newHash, upgraded, err := r.VerifyAndUpgrade(testPBKDF2SHA256, []byte("foo123"), testParams)
+	if err != nil {
+		t.Fatalf("Registry.VerifyAndUpgrade() error = %v", err)
+	}
+	if !upgraded || !strings.HasPrefix(newHash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Errorf("Registry.VerifyAndUpgrade() = %s, %v, expected an argon2id hash", newHash, upgraded)
+	}
+
+	// The upgraded hash verifies and is not upgraded again.
+	again, upgraded, err := r.VerifyAndUpgrade(newHash, []byte("foo123"), testParams)
+	if err != nil || upgraded || again != newHash {
+		t.Errorf("Registry.VerifyAndUpgrade() = %s, %v, %v, expected the same hash", again, upgraded, err)
+	}
+
+	if _, _, err = r.VerifyAndUpgrade(testBcrypt, []byte("foo124"), testParams); !errors.Is(err, ErrPasswordNotMatch) {
+		t.Errorf("Registry.VerifyAndUpgrade() error = %v, expectation = %v", err, ErrPasswordNotMatch)
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	r := NewRegistry(nil)
+
+	var compared string
+	r.Register("$legacy$", VerifierFunc(func(hash string, pass []byte) error {
+		compared = hash
+		if string(pass) != "foo123" {
+			return ErrPasswordNotMatch
+		}
+		return nil
+	}))
+
+	if err := r.Compare("$legacy$abc", []byte("foo123")); err != nil {
+		t.Errorf("Registry.Compare() error = %v", err)
+	}
+	if compared != "$legacy$abc" {
+		t.Errorf("Registry.Compare() passed %q to the verifier", compared)
+	}
+
+	// The longest prefix wins over the built-in bcrypt verifier.
+	r.Register("$2a$04$", VerifierFunc(func(string, []byte) error { return ErrPasswordNotMatch }))
+	if err := r.Compare(testBcrypt, []byte("foo123")); !errors.Is(err, ErrPasswordNotMatch) {
+		t.Errorf("Registry.Compare() error = %v, expectation = %v", err, ErrPasswordNotMatch)
+	}
+	if err := r.Compare(strings.Replace(testBcrypt, "$2a$", "$2b$", 1), []byte("foo123")); err != nil {
+		t.Errorf("Registry.Compare() error = %v", err)
+	}
+}
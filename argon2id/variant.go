@@ -0,0 +1,61 @@
+package argon2id
+
+import argon2core "github.com/gohango/argon2id/argon2id/internal/argon2"
+
+// Variant identifies the argon2 algorithm used to hash a password.
+// The zero value is Argon2id.
+type Variant int
+
+const (
+	// Argon2id mixes data-independent and data-dependent memory access. It is the recommended variant.
+	Argon2id Variant = iota
+	// Argon2i uses data-independent memory access only.
+	Argon2i
+	// Argon2d uses data-dependent memory access only.
+	Argon2d
+)
+
+// variantNames maps each supported variant to its algorithm identifier in the encoded hash.
+var variantNames = map[Variant]string{
+	Argon2id: "argon2id",
+	Argon2i:  "argon2i",
+	Argon2d:  "argon2d",
+}
+
+// String returns the algorithm identifier of the variant, as found in the encoded hash.
+func (v Variant) String() string {
+	if name, ok := variantNames[v]; ok {
+		return name
+	}
+
+	return "unknown"
+}
+
+// valid reports whether the variant is supported.
+func (v Variant) valid() bool {
+	_, ok := variantNames[v]
+	return ok
+}
+
+// mode returns the mode of the internal argon2 implementation matching the variant.
+func (v Variant) mode() argon2core.Mode {
+	switch v {
+	case Argon2i:
+		return argon2core.Argon2i
+	case Argon2d:
+		return argon2core.Argon2d
+	default:
+		return argon2core.Argon2id
+	}
+}
+
+// parseVariant returns the variant matching an algorithm identifier.
+func parseVariant(name string) (Variant, bool) {
+	for v, n := range variantNames {
+		if n == name {
+			return v, true
+		}
+	}
+
+	return 0, false
+}
@@ -0,0 +1,38 @@
+package argon2id
+
+import (
+	argon2core "github.com/gohango/argon2id/argon2id/internal/argon2"
+)
+
+// WorkerPool is a fixed set of goroutines computing the argon2 lanes of the hashing and verification calls of the
+// Hashers sharing it, set with WithWorkerPool. It caps the number of lanes computed at once across all these calls,
+// so that many concurrent calls with a high parallelism do not oversubscribe the CPUs.
+// The hashes are the same as without a pool.
+type WorkerPool struct {
+	pool *argon2core.Pool
+}
+
+// NewWorkerPool starts a pool of the given number of workers, or GOMAXPROCS workers when it is not positive.
+// Call Close to stop them once the Hashers using the pool are not used anymore.
+func NewWorkerPool(workers int) *WorkerPool {
+	return &WorkerPool{pool: argon2core.NewPool(workers)}
+}
+
+// Close stops the workers once they are done with their current lanes.
+// The Hashers using the pool must not be used after it is closed.
+func (p *WorkerPool) Close() {
+	p.pool.Close()
+}
+
+// WithWorkerPool computes the argon2 lanes on the pool, instead of on a new goroutine for each lane of each call.
+// Calls wait for a worker to be free before computing each of their lanes.
+func WithWorkerPool(p *WorkerPool) Option {
+	return func(h *Hasher) error {
+		if p == nil {
+			return ErrInvalidOption
+		}
+
+		h.engine.pool = p.pool
+		return nil
+	}
+}
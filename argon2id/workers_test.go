@@ -0,0 +1,105 @@
+package argon2id
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+func TestHasherWorkerPool(t *testing.T) {
+	p := &Params{Memory: 256, Iterations: 2, Parallelism: 4, SaltLength: 16, KeyLength: 32}
+	salt := bytes.Repeat([]byte{0x2a}, 16)
+
+	expected, err := NewHasher(WithRand(bytes.NewReader(salt)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := expected.Generate([]byte("foo123"), p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool := NewWorkerPool(2)
+	defer pool.Close()
+
+	h, err := NewHasher(WithRand(bytes.NewReader(salt)), WithWorkerPool(pool))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := h.Generate([]byte("foo123"), p)
+	if err != nil {
+		t.Fatalf("Hasher.Generate() error = %v", err)
+	}
+	if hash != want {
+		t.Errorf("Hasher.Generate() = %s, expectation = %s", hash, want)
+	}
+
+	// Hashers share the pool across concurrent calls.
+	peppered, err := NewHasher(WithWorkerPool(pool), WithPepper("k1", []byte("secret")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			if i%2 == 0 {
+				if err := h.Compare(want, []byte("foo123")); err != nil {
+					t.Errorf("Hasher.Compare() error = %v", err)
+				}
+				return
+			}
+
+			hash, err := peppered.Generate([]byte("foo123"), p)
+			if err != nil {
+				t.Errorf("Hasher.Generate() error = %v", err)
+				return
+			}
+			if err = peppered.Compare(hash, []byte("foo123")); err != nil {
+				t.Errorf("Hasher.Compare() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if _, err = NewHasher(WithWorkerPool(nil)); err != ErrInvalidOption {
+		t.Errorf("NewHasher() error = %v, expectation = %v", err, ErrInvalidOption)
+	}
+}
+
+func BenchmarkCompareWorkerPool(b *testing.B) {
+	p := &Params{Memory: 4096, Iterations: 1, Parallelism: 4, SaltLength: 16, KeyLength: 32}
+	hash, err := GenerateFromPassword([]byte("password"), p)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pool := NewWorkerPool(0)
+	defer pool.Close()
+	pooled, err := NewHasher(WithWorkerPool(pool))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bm := range []struct {
+		name string
+		h    *Hasher
+	}{
+		{name: "GoroutinePerLane", h: defaultHasher},
+		{name: "WorkerPool", h: pooled},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.SetParallelism(16)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := bm.h.Compare(hash, []byte("password")); err != nil {
+						b.Error(err)
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package argon2id
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"strconv"
+
+	bcryptcore "github.com/gohango/argon2id/argon2id/internal/bcrypt"
+)
+
+// Algorithms of the legacy hashes that can be wrapped.
+const (
+	// WrapMD5 wraps unsalted MD5 digests of the password, stored as hexadecimal.
+	WrapMD5 = "md5"
+	// WrapSHA1 wraps unsalted SHA-1 digests of the password, stored as hexadecimal.
+	WrapSHA1 = "sha1"
+	// WrapBcrypt wraps bcrypt hashes: $2a$, $2b$ or $2y$, then the cost, the salt and the hash.
+	WrapBcrypt = "bcrypt"
+)
+
+// Wrapping describes the legacy hash an argon2 hash is applied to, in place of the password.
+// It is recorded in the hash with the wrap parameter, plus the wcost and wsalt parameters for bcrypt.
+type Wrapping struct {
+	// Algorithm is the legacy algorithm: WrapMD5, WrapSHA1 or WrapBcrypt.
+	Algorithm string
+	// Cost is the bcrypt cost, and is zero for other algorithms.
+	Cost int
+	// Salt is the encoded bcrypt salt, and is empty for other algorithms.
+	Salt string
+}
+
+// WrapHash protects a legacy hash without knowing the password, by applying argon2 with the given parameters to it.
+// The algorithm is one of WrapMD5, WrapSHA1 or WrapBcrypt.
+// The wrapped hash is verified by CompareHashAndPassword, which recomputes the legacy hash from the password first,
+// and always needs a rehash, so that VerifyAndUpgrade replaces it with a plain argon2 hash on the next login.
+// Returns the string representation with nil error when successful. On failure, it returns empty string with non-nil error.
+func WrapHash(algorithm, legacy string, p *Params) (string, error) {
+	return defaultHasher.Wrap(algorithm, legacy, p)
+}
+
+// Wrap works like WrapHash, using the hasher's parameters when p is nil and mixing the current pepper, if any, into the key.
+func (h *Hasher) Wrap(algorithm, legacy string, p *Params) (string, error) {
+	w, inner, err := parseLegacy(algorithm, legacy)
+	if err != nil {
+		return "", err
+	}
+
+	return h.generate(context.Background(), inner, nil, p, w)
+}
+
+// parseLegacy decodes a legacy hash into its wrapping and the value argon2 is applied to,
+// which inner computes from the password.
+func parseLegacy(algorithm, legacy string) (*Wrapping, []byte, error) {
+	switch algorithm {
+	case WrapMD5, WrapSHA1:
+		size := md5.Size
+		if algorithm == WrapSHA1 {
+			size = sha1.Size
+		}
+
+		digest, err := hex.DecodeString(legacy)
+		if err != nil || len(digest) != size {
+			return nil, nil, ErrInvalidHash
+		}
+
+		return &Wrapping{Algorithm: algorithm}, digest, nil
+	case WrapBcrypt:
+		cost, salt, sum, err := bcryptcore.Parse(legacy)
+		if err != nil {
+			return nil, nil, ErrInvalidHash
+		}
+
+		return &Wrapping{Algorithm: algorithm, Cost: cost, Salt: salt}, sum, nil
+	default:
+		return nil, nil, ErrUnknownAlgorithm
+	}
+}
+
+// inner recomputes the legacy hash of the password, as wrapped by argon2.
+func (w *Wrapping) inner(pass []byte) ([]byte, error) {
+	switch w.Algorithm {
+	case WrapMD5:
+		digest := md5.Sum(pass)
+		return digest[:], nil
+	case WrapSHA1:
+		digest := sha1.Sum(pass)
+		return digest[:], nil
+	case WrapBcrypt:
+		sum, err := bcryptcore.Hash(pass, w.Cost, w.Salt)
+		if err != nil {
+			return nil, ErrInvalidHash
+		}
+		return sum, nil
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+}
+
+// params returns the PHC parameters recording the wrapping.
+func (w *Wrapping) params() []phcParam {
+	params := []phcParam{{name: "wrap", value: w.Algorithm}}
+	if w.Algorithm == WrapBcrypt {
+		params = append(params,
+			phcParam{name: "wcost", value: strconv.Itoa(w.Cost)},
+			phcParam{name: "wsalt", value: w.Salt},
+		)
+	}
+
+	return params
+}
+
+// decodeWrapping decodes the wrap, wcost and wsalt parameters of a hash, given by name.
+func decodeWrapping(values map[string]string) (*Wrapping, error) {
+	w := &Wrapping{Algorithm: values["wrap"]}
+
+	switch w.Algorithm {
+	case "":
+		return nil, ErrInvalidHash
+	case WrapMD5, WrapSHA1:
+		if len(values) != 1 {
+			return nil, ErrInvalidHash
+		}
+	case WrapBcrypt:
+		if len(values) != 3 {
+			return nil, ErrInvalidHash
+		}
+
+		cost, err := parseDecimal(values["wcost"], 8)
+		if err != nil {
+			return nil, err
+		}
+		w.Cost = int(cost)
+		w.Salt = values["wsalt"]
+
+		if w.Cost < bcryptcore.MinCost || w.Cost > bcryptcore.MaxCost || bcryptcore.CheckSalt(w.Salt) != nil {
+			return nil, ErrInvalidHash
+		}
+	default:
+		return nil, &UnsupportedError{Field: FieldParams, Value: w.Algorithm, Err: ErrUnknownAlgorithm}
+	}
+
+	return w, nil
+}
@@ -0,0 +1,228 @@
+package argon2id
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestWrapHash(t *testing.T) {
+	md5Sum := md5.Sum([]byte("foo123"))
+	sha1Sum := sha1.Sum([]byte("foo123"))
+
+	tests := []struct {
+		name        string
+		algorithm   string
+		legacy      string
+		wantParams  string
+		wantErr     bool
+		expectedErr error
+	}{
+		{
+			name:       "MD5",
+			algorithm:  WrapMD5,
+			legacy:     hex.EncodeToString(md5Sum[:]),
+			wantParams: "$m=64,t=1,p=1,wrap=md5$",
+		},
+		{
+			name:       "Uppercase MD5",
+			algorithm:  WrapMD5,
+			legacy:     strings.ToUpper(hex.EncodeToString(md5Sum[:])),
+			wantParams: "$m=64,t=1,p=1,wrap=md5$",
+		},
+		{
+			name:       "SHA1",
+			algorithm:  WrapSHA1,
+			legacy:     hex.EncodeToString(sha1Sum[:]),
+			wantParams: "$m=64,t=1,p=1,wrap=sha1$",
+		},
+		{
+			name:       "Bcrypt",
+			algorithm:  WrapBcrypt,
+			legacy:     testBcrypt,
+			wantParams: "$m=64,t=1,p=1,wrap=bcrypt,wcost=4,wsalt=ZuaOqr6cmvAro0vFQruqCe$",
+		},
+		{
+			name:        "MD5 of the wrong length",
+			algorithm:   WrapMD5,
+			legacy:      hex.EncodeToString(sha1Sum[:]),
+			wantErr:     true,
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Invalid bcrypt",
+			algorithm:   WrapBcrypt,
+			legacy:      testBcrypt[:50],
+			wantErr:     true,
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Unknown algorithm",
+			algorithm:   "md4",
+			legacy:      hex.EncodeToString(md5Sum[:]),
+			wantErr:     true,
+			expectedErr: ErrUnknownAlgorithm,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := WrapHash(tt.algorithm, tt.legacy, testParams)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WrapHash() error = %v, wantErr = %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, tt.expectedErr) {
+					t.Errorf("WrapHash() error = %v, expectation = %v", err, tt.expectedErr)
+				}
+				return
+			}
+
+			if !strings.Contains(hash, tt.wantParams) {
+				t.Errorf("WrapHash() = %s, expected params %s", hash, tt.wantParams)
+			}
+
+			if err = CompareHashAndPassword(hash, []byte("foo123")); err != nil {
+				t.Errorf("CompareHashAndPassword() error = %v", err)
+			}
+			if err = CompareHashAndPassword(hash, []byte("foo124")); !errors.Is(err, ErrPasswordNotMatch) {
+				t.Errorf("CompareHashAndPassword() error = %v, expectation = %v", err, ErrPasswordNotMatch)
+			}
+
+			// The hash round-trips through Parse.
+			decoded, err := Parse(hash)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if decoded.String() != hash {
+				t.Errorf("Hash.String() = %s, expectation = %s", decoded.String(), hash)
+			}
+
+			// Wrapped hashes are upgraded to plain ones on the next login.
+			newHash, upgraded, err := VerifyAndUpgrade(hash, []byte("foo123"), testParams)
+			if err != nil || !upgraded || strings.Contains(newHash, "wrap=") {
+				t.Errorf("VerifyAndUpgrade() = %s, %v, %v, expected a plain hash", newHash, upgraded, err)
+			}
+		})
+	}
+}
+
+func TestHasherWrapPepper(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("foo123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h, err := NewHasher(WithPepper("k1", []byte("secret")), WithParams(testParams))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := h.Wrap(WrapBcrypt, strings.Replace(string(legacy), "$2a$", "$2y$", 1), nil)
+	if err != nil {
+		t.Fatalf("Hasher.Wrap() error = %v", err)
+	}
+	if !strings.Contains(hash, ",keyid=azE,wrap=bcrypt,") {
+		t.Errorf("Hasher.Wrap() = %s, expected the keyid and wrap parameters", hash)
+	}
+
+	if err = h.Compare(hash, []byte("foo123")); err != nil {
+		t.Errorf("Hasher.Compare() error = %v", err)
+	}
+	if rehash, err := h.NeedsRehash(hash, nil); err != nil || !rehash {
+		t.Errorf("Hasher.NeedsRehash() = %v, %v, expectation = true", rehash, err)
+	}
+}
+
+func TestWrapLimits(t *testing.T) {
+	hash, err := WrapHash(WrapBcrypt, testBcrypt, testParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limits := &Limits{MaxBcryptCost: 4}
+
+	if err = CompareHashAndPasswordWithLimits(hash, []byte("foo123"), limits); err != nil {
+		t.Errorf("CompareHashAndPasswordWithLimits() error = %v", err)
+	}
+
+	// A tampered cost is rejected before bcrypt runs.
+	inflated := strings.Replace(hash, "wcost=4", "wcost=31", 1)
+	err = CompareHashAndPasswordWithLimits(inflated, []byte("foo123"), limits)
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.Param != "wcost" || limitErr.Value != 31 {
+		t.Errorf("CompareHashAndPasswordWithLimits() error = %v, expected the wcost above its limit", err)
+	}
+
+	h, err := NewHasher(WithLimits(limits))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = h.Compare(inflated, []byte("foo123")); !errors.Is(err, ErrLimitExceeded) {
+		t.Errorf("Hasher.Compare() error = %v, expectation = %v", err, ErrLimitExceeded)
+	}
+
+	// Limits set before MaxBcryptCost existed still cap the cost, at DefaultMaxBcryptCost.
+	err = CompareHashAndPasswordWithLimits(inflated, []byte("foo123"), &Limits{MaxMemory: 64 * 1024})
+	if !errors.As(err, &limitErr) || limitErr.Param != "wcost" || limitErr.Max != DefaultMaxBcryptCost {
+		t.Errorf("CompareHashAndPasswordWithLimits() error = %v, expected the wcost above %d", err, DefaultMaxBcryptCost)
+	}
+	if err = CompareHashAndPasswordWithLimits(hash, []byte("foo123"), &Limits{MaxMemory: 64 * 1024}); err != nil {
+		t.Errorf("CompareHashAndPasswordWithLimits() error = %v", err)
+	}
+}
+
+func TestDecodeWrapping(t *testing.T) {
+	const prefix = "$argon2id$v=19$m=64,t=1,p=1,"
+	const suffix = "$c29tZXNhbHQ$c29tZWhhc2g"
+
+	tests := []struct {
+		name        string
+		params      string
+		expectedErr error
+	}{
+		{
+			name:        "Unknown algorithm",
+			params:      "wrap=md4",
+			expectedErr: ErrUnknownAlgorithm,
+		},
+		{
+			name:        "Bcrypt parameters without wrap",
+			params:      "wcost=4,wsalt=ZuaOqr6cmvAro0vFQruqCe",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Bcrypt without salt",
+			params:      "wrap=bcrypt,wcost=4",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Bcrypt cost out of range",
+			params:      "wrap=bcrypt,wcost=40,wsalt=ZuaOqr6cmvAro0vFQruqCe",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "Bcrypt salt too short",
+			params:      "wrap=bcrypt,wcost=4,wsalt=ZuaOqr6cmvAro0vFQruqC",
+			expectedErr: ErrInvalidHash,
+		},
+		{
+			name:        "MD5 with a salt",
+			params:      "wrap=md5,wsalt=ZuaOqr6cmvAro0vFQruqCe",
+			expectedErr: ErrInvalidHash,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(prefix + tt.params + suffix); !errors.Is(err, tt.expectedErr) {
+				t.Errorf("Parse() error = %v, expectation = %v", err, tt.expectedErr)
+			}
+		})
+	}
+}
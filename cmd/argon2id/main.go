@@ -0,0 +1,334 @@
+// Command argon2id hashes, verifies, inspects and calibrates argon2id password hashes.
+//
+// Usage:
+//
+//	argon2id hash [-preset name] [-m memory] [-t iterations] [-p parallelism] [-salt length] [-key length]
+//	argon2id verify <hash>
+//	argon2id inspect [-json] <hash>
+//	argon2id calibrate [-target duration] [-max-memory memory] [-p parallelism] [-json]
+//
+// Passwords are read from a no-echo prompt when stdin is a terminal, or from the first line of stdin otherwise.
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gohango/argon2id/argon2id"
+	"golang.org/x/term"
+)
+
+// Exit codes returned by the command.
+const (
+	exitOK       = 0
+	exitFailure  = 1
+	exitUsage    = 2
+	exitMismatch = 3
+)
+
+const usage = `Usage: argon2id <command> [flags]
+
+Commands:
+  hash       hash a password and print its PHC string
+  verify     verify a password against a hash, exiting with status 3 on mismatch
+  inspect    print the parameters of a hash
+  calibrate  print parameters tuned to a target duration on this machine
+
+Run "argon2id <command> -h" for the flags of a command.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return exitUsage
+	}
+
+	var cmd func([]string, io.Reader, io.Writer, io.Writer) int
+	switch args[0] {
+	case "hash":
+		cmd = runHash
+	case "verify":
+		cmd = runVerify
+	case "inspect":
+		cmd = runInspect
+	case "calibrate":
+		cmd = runCalibrate
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return exitOK
+	default:
+		fmt.Fprintf(stderr, "argon2id: unknown command %q\n\n%s", args[0], usage)
+		return exitUsage
+	}
+
+	return cmd(args[1:], stdin, stdout, stderr)
+}
+
+func runHash(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("hash", stderr)
+	preset := fs.String("preset", argon2id.DefaultPreset().Name, "name of the parameter preset")
+	memory := fs.Uint("m", 0, "memory in KiB, overriding the preset")
+	iterations := fs.Uint("t", 0, "number of iterations, overriding the preset")
+	parallelism := fs.Uint("p", 0, "degree of parallelism, overriding the preset")
+	saltLength := fs.Uint("salt", 0, "salt length in bytes, overriding the preset")
+	keyLength := fs.Uint("key", 0, "key length in bytes, overriding the preset")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	pr, ok := argon2id.LookupPreset(*preset)
+	if !ok {
+		fmt.Fprintf(stderr, "argon2id: unknown preset %q\n", *preset)
+		return exitUsage
+	}
+
+	maxUint32 := uint64(^uint32(0))
+	if uint64(*memory) > maxUint32 || uint64(*iterations) > maxUint32 || *parallelism > 255 ||
+		uint64(*saltLength) > maxUint32 || uint64(*keyLength) > maxUint32 {
+		fmt.Fprintln(stderr, "argon2id: parameters out of range")
+		return exitUsage
+	}
+
+	p := pr.Params
+	if *memory > 0 {
+		p.Memory = uint32(*memory)
+	}
+	if *iterations > 0 {
+		p.Iterations = uint32(*iterations)
+	}
+	if *parallelism > 0 {
+		p.Parallelism = uint8(*parallelism)
+	}
+	if *saltLength > 0 {
+		p.SaltLength = uint32(*saltLength)
+	}
+	if *keyLength > 0 {
+		p.KeyLength = uint32(*keyLength)
+	}
+
+	pass, err := readPassword(stdin, stderr, "Password: ")
+	if err != nil {
+		fmt.Fprintf(stderr, "argon2id: %v\n", err)
+		return exitFailure
+	}
+
+	hash, err := argon2id.GenerateFromPassword(pass, &p)
+	if err != nil {
+		fmt.Fprintf(stderr, "argon2id: %v\n", err)
+		return exitFailure
+	}
+
+	fmt.Fprintln(stdout, hash)
+	return exitOK
+}
+
+func runVerify(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("verify", stderr)
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "argon2id: verify expects exactly one hash")
+		return exitUsage
+	}
+
+	pass, err := readPassword(stdin, stderr, "Password: ")
+	if err != nil {
+		fmt.Fprintf(stderr, "argon2id: %v\n", err)
+		return exitFailure
+	}
+
+	err = argon2id.CompareHashAndPassword(fs.Arg(0), pass)
+	switch {
+	case err == nil:
+		fmt.Fprintln(stdout, "OK")
+		return exitOK
+	case errors.Is(err, argon2id.ErrPasswordNotMatch):
+		fmt.Fprintln(stderr, "argon2id: password does not match")
+		return exitMismatch
+	default:
+		fmt.Fprintf(stderr, "argon2id: %v\n", err)
+		return exitFailure
+	}
+}
+
+// inspection is the printed description of a hash.
+type inspection struct {
+	Algorithm   string `json:"algorithm"`
+	Version     int    `json:"version"`
+	Memory      uint32 `json:"memory"`
+	Iterations  uint32 `json:"iterations"`
+	Parallelism uint8  `json:"parallelism"`
+	SaltLength  uint32 `json:"salt_length"`
+	KeyLength   uint32 `json:"key_length"`
+	Salt        string `json:"salt"`
+	Key         string `json:"key"`
+	// The optional parts of the hash are omitted when absent.
+	KeyID         string `json:"key_id,omitempty"`
+	Data          string `json:"data,omitempty"`
+	Normalization string `json:"normalization,omitempty"`
+	Wrap          string `json:"wrap,omitempty"`
+	WrapCost      int    `json:"wrap_cost,omitempty"`
+	WrapSalt      string `json:"wrap_salt,omitempty"`
+}
+
+func runInspect(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("inspect", stderr)
+	asJSON := fs.Bool("json", false, "print the parameters as JSON")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(stderr, "argon2id: inspect expects exactly one hash")
+		return exitUsage
+	}
+
+	h, err := argon2id.Parse(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "argon2id: %v\n", err)
+		return exitFailure
+	}
+
+	i := inspection{
+		Algorithm:   h.Algorithm(),
+		Version:     h.Version,
+		Memory:      h.Params.Memory,
+		Iterations:  h.Params.Iterations,
+		Parallelism: h.Params.Parallelism,
+		SaltLength:  h.Params.SaltLength,
+		KeyLength:   h.Params.KeyLength,
+		Salt:        base64.RawStdEncoding.EncodeToString(h.Salt),
+		Key:         base64.RawStdEncoding.EncodeToString(h.Key),
+		// The key identifier and associated data are printed as encoded in the hash.
+		KeyID:         base64.RawStdEncoding.EncodeToString(h.KeyID),
+		Data:          base64.RawStdEncoding.EncodeToString(h.Data),
+		Normalization: h.Normalization,
+	}
+	if h.Wrap != nil {
+		i.Wrap, i.WrapCost, i.WrapSalt = h.Wrap.Algorithm, h.Wrap.Cost, h.Wrap.Salt
+	}
+
+	if *asJSON {
+		return printJSON(stdout, stderr, i)
+	}
+
+	fmt.Fprintf(stdout, "algorithm:   %s\n", i.Algorithm)
+	fmt.Fprintf(stdout, "version:     %d\n", i.Version)
+	fmt.Fprintf(stdout, "memory:      %d KiB\n", i.Memory)
+	fmt.Fprintf(stdout, "iterations:  %d\n", i.Iterations)
+	fmt.Fprintf(stdout, "parallelism: %d\n", i.Parallelism)
+	if i.KeyID != "" {
+		fmt.Fprintf(stdout, "key id:      %s\n", i.KeyID)
+	}
+	if i.Data != "" {
+		fmt.Fprintf(stdout, "data:        %s\n", i.Data)
+	}
+	if i.Normalization != "" {
+		fmt.Fprintf(stdout, "normalized:  %s\n", i.Normalization)
+	}
+	switch {
+	case i.WrapSalt != "":
+		fmt.Fprintf(stdout, "wrapped:     %s (cost %d, salt %s)\n", i.Wrap, i.WrapCost, i.WrapSalt)
+	case i.Wrap != "":
+		fmt.Fprintf(stdout, "wrapped:     %s\n", i.Wrap)
+	}
+	fmt.Fprintf(stdout, "salt:        %s (%d bytes)\n", i.Salt, i.SaltLength)
+	fmt.Fprintf(stdout, "key:         %s (%d bytes)\n", i.Key, i.KeyLength)
+	return exitOK
+}
+
+// calibration is the printed result of a calibration, with the JSON keys of inspection.
+type calibration struct {
+	Algorithm   string `json:"algorithm"`
+	Memory      uint32 `json:"memory"`
+	Iterations  uint32 `json:"iterations"`
+	Parallelism uint8  `json:"parallelism"`
+	SaltLength  uint32 `json:"salt_length"`
+	KeyLength   uint32 `json:"key_length"`
+}
+
+func runCalibrate(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := newFlagSet("calibrate", stderr)
+	target := fs.Duration("target", 500*time.Millisecond, "target hashing duration")
+	maxMemory := fs.Uint("max-memory", 64*1024, "maximum memory in KiB")
+	parallelism := fs.Uint("p", 1, "degree of parallelism")
+	asJSON := fs.Bool("json", false, "print the parameters as JSON")
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+	if *parallelism > 255 || uint64(*maxMemory) > uint64(^uint32(0)) {
+		fmt.Fprintln(stderr, "argon2id: parameters out of range")
+		return exitUsage
+	}
+
+	p, err := argon2id.Calibrate(*target, uint32(*maxMemory), uint8(*parallelism))
+	if err != nil {
+		fmt.Fprintf(stderr, "argon2id: %v\n", err)
+		return exitFailure
+	}
+
+	if *asJSON {
+		return printJSON(stdout, stderr, calibration{
+			Algorithm:   p.Variant.String(),
+			Memory:      p.Memory,
+			Iterations:  p.Iterations,
+			Parallelism: p.Parallelism,
+			SaltLength:  p.SaltLength,
+			KeyLength:   p.KeyLength,
+		})
+	}
+
+	fmt.Fprintf(stdout, "m=%d,t=%d,p=%d\n", p.Memory, p.Iterations, p.Parallelism)
+	return exitOK
+}
+
+// newFlagSet returns a flag set reporting its errors and usage to stderr.
+func newFlagSet(name string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet("argon2id "+name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	return fs
+}
+
+// printJSON writes v as indented JSON.
+func printJSON(stdout, stderr io.Writer, v interface{}) int {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(stderr, "argon2id: %v\n", err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+// readPassword reads a password from a no-echo prompt when stdin is a terminal, or from the first line of stdin otherwise.
+func readPassword(stdin io.Reader, stderr io.Writer, prompt string) ([]byte, error) {
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(stderr, prompt)
+		pass, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(stderr)
+		return pass, err
+	}
+
+	line, err := bufio.NewReader(stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if line == "" && err == io.EOF {
+		return nil, errors.New("no password given on stdin")
+	}
+
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testHash = "$argon2id$v=19$m=4096,t=3,p=1$82XldKYgqAqher7EuFzPNw$O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM"
+
+// testInspectHead and testInspectTail are the lines inspect prints for testHash around its optional parameters.
+const (
+	testInspectHead = "algorithm:   argon2id\nversion:     19\nmemory:      4096 KiB\niterations:  3\nparallelism: 1\n"
+	testInspectTail = "salt:        82XldKYgqAqher7EuFzPNw (16 bytes)\nkey:         O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM (32 bytes)\n"
+)
+
+// withParams returns testHash with the optional parameters appended to its parameters.
+func withParams(params string) string {
+	return strings.Replace(testHash, "p=1$", "p=1,"+params+"$", 1)
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		stdin      string
+		wantCode   int
+		wantStdout string
+	}{
+		{
+			name:     "No command",
+			args:     nil,
+			wantCode: exitUsage,
+		},
+		{
+			name:     "Unknown command",
+			args:     []string{"frobnicate"},
+			wantCode: exitUsage,
+		},
+		{
+			name:       "Verify a matching password",
+			args:       []string{"verify", testHash},
+			stdin:      "foo123\n",
+			wantCode:   exitOK,
+			wantStdout: "OK\n",
+		},
+		{
+			name:     "Verify a wrong password",
+			args:     []string{"verify", testHash},
+			stdin:    "foo124\n",
+			wantCode: exitMismatch,
+		},
+		{
+			name:     "Verify an invalid hash",
+			args:     []string{"verify", "$argon2id$m=4096"},
+			stdin:    "foo123\n",
+			wantCode: exitFailure,
+		},
+		{
+			name:     "Verify without a hash",
+			args:     []string{"verify"},
+			stdin:    "foo123\n",
+			wantCode: exitUsage,
+		},
+		{
+			name:       "Inspect a hash",
+			args:       []string{"inspect", testHash},
+			wantCode:   exitOK,
+			wantStdout: "algorithm:   argon2id\nversion:     19\nmemory:      4096 KiB\niterations:  3\nparallelism: 1\nsalt:        82XldKYgqAqher7EuFzPNw (16 bytes)\nkey:         O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM (32 bytes)\n",
+		},
+		{
+			name:       "Inspect a hash with a key id",
+			args:       []string{"inspect", withParams("keyid=azE")},
+			wantCode:   exitOK,
+			wantStdout: testInspectHead + "key id:      azE\n" + testInspectTail,
+		},
+		{
+			name:       "Inspect a hash with associated data",
+			args:       []string{"inspect", withParams("data=dXNlcjQy")},
+			wantCode:   exitOK,
+			wantStdout: testInspectHead + "data:        dXNlcjQy\n" + testInspectTail,
+		},
+		{
+			name:       "Inspect a normalized hash",
+			args:       []string{"inspect", withParams("norm=opaquestring")},
+			wantCode:   exitOK,
+			wantStdout: testInspectHead + "normalized:  opaquestring\n" + testInspectTail,
+		},
+		{
+			name:       "Inspect a wrapped bcrypt hash",
+			args:       []string{"inspect", withParams("wrap=bcrypt,wcost=4,wsalt=ZuaOqr6cmvAro0vFQruqCe")},
+			wantCode:   exitOK,
+			wantStdout: testInspectHead + "wrapped:     bcrypt (cost 4, salt ZuaOqr6cmvAro0vFQruqCe)\n" + testInspectTail,
+		},
+		{
+			name:       "Inspect a wrapped MD5 hash",
+			args:       []string{"inspect", withParams("wrap=md5")},
+			wantCode:   exitOK,
+			wantStdout: testInspectHead + "wrapped:     md5\n" + testInspectTail,
+		},
+		{
+			name:     "Hash with an unknown preset",
+			args:     []string{"hash", "-preset", "unknown"},
+			stdin:    "foo123\n",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "Hash with memory out of range",
+			args:     []string{"hash", "-m", "4294967360"},
+			stdin:    "foo123\n",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "Hash with iterations out of range",
+			args:     []string{"hash", "-t", "4294967297"},
+			stdin:    "foo123\n",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "Hash with parallelism out of range",
+			args:     []string{"hash", "-p", "256"},
+			stdin:    "foo123\n",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "Hash with a salt length out of range",
+			args:     []string{"hash", "-salt", "4294967312"},
+			stdin:    "foo123\n",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "Hash with a key length out of range",
+			args:     []string{"hash", "-key", "4294967328"},
+			stdin:    "foo123\n",
+			wantCode: exitUsage,
+		},
+		{
+			name:     "Hash without a password",
+			args:     []string{"hash"},
+			stdin:    "",
+			wantCode: exitFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(tt.args, strings.NewReader(tt.stdin), &stdout, &stderr)
+			if code != tt.wantCode {
+				t.Errorf("run() = %d, expectation = %d, stderr = %s", code, tt.wantCode, stderr.String())
+			}
+
+			if tt.wantStdout != "" && stdout.String() != tt.wantStdout {
+				t.Errorf("run() stdout = %q, expectation = %q", stdout.String(), tt.wantStdout)
+			}
+		})
+	}
+}
+
+func TestRunInspectJSON(t *testing.T) {
+	plain := inspection{
+		Algorithm: "argon2id", Version: 19, Memory: 4096, Iterations: 3, Parallelism: 1, SaltLength: 16, KeyLength: 32,
+		Salt: "82XldKYgqAqher7EuFzPNw", Key: "O1Epnr+m1JYkgtWcgVLID39ro6He105HTFnE+SinJyM",
+	}
+	keyID := plain
+	keyID.KeyID = "azE"
+	data := plain
+	data.Data = "dXNlcjQy"
+	normalized := plain
+	normalized.Normalization = "opaquestring"
+	wrapped := plain
+	wrapped.Wrap, wrapped.WrapCost, wrapped.WrapSalt = "bcrypt", 4, "ZuaOqr6cmvAro0vFQruqCe"
+
+	tests := []struct {
+		name string
+		hash string
+		want inspection
+		// keys are the printed optional keys.
+		keys []string
+	}{
+		{name: "Plain hash", hash: testHash, want: plain},
+		{name: "Key id", hash: withParams("keyid=azE"), want: keyID, keys: []string{"key_id"}},
+		{name: "Associated data", hash: withParams("data=dXNlcjQy"), want: data, keys: []string{"data"}},
+		{name: "Normalization", hash: withParams("norm=opaquestring"), want: normalized, keys: []string{"normalization"}},
+		{
+			name: "Wrapped bcrypt", hash: withParams("wrap=bcrypt,wcost=4,wsalt=ZuaOqr6cmvAro0vFQruqCe"), want: wrapped,
+			keys: []string{"wrap", "wrap_cost", "wrap_salt"},
+		},
+	}
+
+	required := []string{"algorithm", "version", "memory", "iterations", "parallelism", "salt_length", "key_length", "salt", "key"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run([]string{"inspect", "-json", tt.hash}, nil, &stdout, &stderr); code != exitOK {
+				t.Fatalf("run(inspect) = %d, stderr = %s", code, stderr.String())
+			}
+
+			var i inspection
+			if err := json.Unmarshal(stdout.Bytes(), &i); err != nil {
+				t.Fatalf("run(inspect) printed invalid JSON: %v", err)
+			}
+			if i != tt.want {
+				t.Errorf("run(inspect) = %+v, expectation = %+v", i, tt.want)
+			}
+
+			// Absent optional parameters are omitted.
+			var fields map[string]interface{}
+			if err := json.Unmarshal(stdout.Bytes(), &fields); err != nil {
+				t.Fatal(err)
+			}
+			keys := append(append([]string(nil), required...), tt.keys...)
+			if len(fields) != len(keys) {
+				t.Errorf("run(inspect) printed %d keys, expectation = %v", len(fields), keys)
+			}
+			for _, key := range keys {
+				if _, ok := fields[key]; !ok {
+					t.Errorf("run(inspect) did not print %q", key)
+				}
+			}
+		})
+	}
+}
+
+func TestRunHashThenVerify(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"hash", "-m", "64", "-t", "1"}, strings.NewReader("foo123\n"), &stdout, &stderr); code != exitOK {
+		t.Fatalf("run(hash) = %d, stderr = %s", code, stderr.String())
+	}
+
+	hash := strings.TrimSpace(stdout.String())
+	if !strings.HasPrefix(hash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("run(hash) printed %s", hash)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"inspect", "-json", hash}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run(inspect) = %d, stderr = %s", code, stderr.String())
+	}
+
+	var i inspection
+	if err := json.Unmarshal(stdout.Bytes(), &i); err != nil {
+		t.Fatalf("run(inspect) printed invalid JSON: %v", err)
+	}
+	if i.Memory != 64 || i.Iterations != 1 || i.Parallelism != 1 {
+		t.Errorf("run(inspect) = %+v", i)
+	}
+
+	if code := run([]string{"verify", hash}, strings.NewReader("foo123"), &stdout, &stderr); code != exitOK {
+		t.Errorf("run(verify) = %d, stderr = %s", code, stderr.String())
+	}
+}
+
+func TestRunCalibrateJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"calibrate", "-json", "-target", "1ms", "-max-memory", "64"}, nil, &stdout, &stderr); code != exitOK {
+		t.Fatalf("run(calibrate) = %d, stderr = %s", code, stderr.String())
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &fields); err != nil {
+		t.Fatalf("run(calibrate) printed invalid JSON: %v", err)
+	}
+	for _, key := range []string{"algorithm", "memory", "iterations", "parallelism", "salt_length", "key_length"} {
+		if _, ok := fields[key]; !ok {
+			t.Errorf("run(calibrate) did not print %q", key)
+		}
+	}
+	if len(fields) != 6 || fields["algorithm"] != "argon2id" || fields["memory"] != float64(64) {
+		t.Errorf("run(calibrate) = %s", stdout.String())
+	}
+}
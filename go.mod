@@ -2,4 +2,9 @@ module github.com/gohango/argon2id
 
 go 1.16
 
-require golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a
+require (
+	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a
+	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68
+	golang.org/x/term v0.0.0-20201126162022-7de9c90e9dd1
+	golang.org/x/text v0.3.6
+)